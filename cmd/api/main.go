@@ -2,13 +2,23 @@ package main
 
 import (
 	"log"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/yourusername/ecommerce/configs"
+	"github.com/yourusername/ecommerce/internal/account"
+	"github.com/yourusername/ecommerce/internal/audit"
+	"github.com/yourusername/ecommerce/internal/auth"
+	"github.com/yourusername/ecommerce/internal/catalog"
 	"github.com/yourusername/ecommerce/internal/database"
+	"github.com/yourusername/ecommerce/internal/geoip"
 	"github.com/yourusername/ecommerce/internal/handlers"
 	"github.com/yourusername/ecommerce/internal/middleware"
 	"github.com/yourusername/ecommerce/internal/models"
+	"github.com/yourusername/ecommerce/internal/password"
+	"github.com/yourusername/ecommerce/internal/rbac"
+	"github.com/yourusername/ecommerce/internal/search"
+	"github.com/yourusername/ecommerce/internal/throttle"
 )
 
 func main() {
@@ -25,34 +35,154 @@ func main() {
 		&models.Category{},
 		&models.Product{},
 		&models.Image{},
+		&models.OptionType{},
+		&models.OptionValue{},
+		&models.Variant{},
 		&models.Order{},
 		&models.OrderItem{},
 		&models.ShippingInfo{},
+		&models.RefreshToken{},
+		&models.RevokedToken{},
+		&models.PasswordResetToken{},
+		&models.RecoveryCode{},
+		&models.UserIdentity{},
+		&models.OAuthState{},
+		&models.LoginAttempt{},
+		&models.Role{},
+		&models.Permission{},
+		&models.APIKey{},
+		&models.DataExport{},
+		&models.Session{},
 	)
 
+	if err := audit.Migrate(db); err != nil {
+		log.Fatalf("Failed to migrate audit log: %v", err)
+	}
+
+	if err := catalog.Migrate(db); err != nil {
+		log.Fatalf("Failed to migrate categories: %v", err)
+	}
+	if err := catalog.MigrateVariants(db); err != nil {
+		log.Fatalf("Failed to migrate product variants: %v", err)
+	}
+	if err := search.Migrate(db, config.SearchLanguage); err != nil {
+		log.Fatalf("Failed to set up product search: %v", err)
+	}
+
+	// Create the built-in roles and permissions
+	if err := rbac.Seed(db); err != nil {
+		log.Fatalf("Failed to seed roles: %v", err)
+	}
+	if config.BootstrapAdminEmail != "" {
+		promoted, err := rbac.PromoteAdmin(db, config.BootstrapAdminEmail)
+		if err != nil {
+			log.Fatalf("Failed to promote bootstrap admin: %v", err)
+		}
+		if promoted {
+			log.Printf("Promoted %s to admin", config.BootstrapAdminEmail)
+		}
+	}
+
+	// Periodically purge expired refresh tokens and revocation entries
+	go func() {
+		for range time.Tick(time.Hour) {
+			if err := auth.PurgeExpiredTokens(); err != nil {
+				log.Printf("Failed to purge expired tokens: %v", err)
+			}
+			if err := account.PurgeExpiredExports(); err != nil {
+				log.Printf("Failed to purge expired exports: %v", err)
+			}
+		}
+	}()
+
+	// Load JWT signing keys and rotate them in the background
+	if err := auth.InitKeyRing(config); err != nil {
+		log.Fatalf("Failed to load signing keys: %v", err)
+	}
+	auth.GetKeyRing().StartRotation(time.Hour)
+
+	// Login throttling shared by the login and admin handlers
+	loginGuard := throttle.NewLoginGuard(config)
+	go func() {
+		for range time.Tick(10 * time.Minute) {
+			if err := loginGuard.Purge(); err != nil {
+				log.Printf("Failed to purge login attempts: %v", err)
+			}
+		}
+	}()
+
+	// Session last-seen times are recorded in memory and written once a minute
+	auth.StartSessionActivityFlusher(time.Minute)
+
+	// Password hashing scheme, policy and breached password list
+	if err := password.InitHasher(config); err != nil {
+		log.Fatalf("Failed to configure password hashing: %v", err)
+	}
+	passwordPolicy, err := password.NewValidator(config)
+	if err != nil {
+		log.Fatalf("Failed to load password policy: %v", err)
+	}
+
+	// Pagination cursors are signed with their own secret, never the JWT one
+	if config.CursorSecret == "" {
+		secret, err := auth.NewOpaqueToken()
+		if err != nil {
+			log.Fatalf("Failed to generate cursor secret: %v", err)
+		}
+		config.CursorSecret = secret
+		log.Println("Warning: CURSOR_SECRET not set, using a random secret; cursors won't survive a restart or work across instances")
+	}
+
+	// Optional GeoIP database for showing where sessions are
+	var geo *geoip.Database
+	if config.GeoIPFile != "" {
+		geo, err = geoip.LoadCSV(config.GeoIPFile)
+		if err != nil {
+			log.Fatalf("Failed to load GeoIP database: %v", err)
+		}
+	}
+
 	// Initialize handlers
-	userHandler := handlers.NewUserHandler(config)
-	productHandler := handlers.NewProductHandler()
-	orderHandler := handlers.NewOrderHandler()
+	userHandler := handlers.NewUserHandler(config, loginGuard, passwordPolicy)
+	productHandler := handlers.NewProductHandler(config)
+	categoryHandler := handlers.NewCategoryHandler()
+	variantHandler := handlers.NewVariantHandler()
+	orderHandler := handlers.NewOrderHandler(config)
 	paymentHandler := handlers.NewPaymentHandler(config)
+	keyHandler := handlers.NewKeyHandler()
+	mfaHandler := handlers.NewMFAHandler(config, loginGuard)
+	oidcHandler := handlers.NewOIDCHandler(config)
+	magicLinkHandler := handlers.NewMagicLinkHandler(config, loginGuard)
+	adminHandler := handlers.NewAdminHandler(config, loginGuard)
+	roleHandler := handlers.NewRoleHandler()
+	apiKeyHandler := handlers.NewAPIKeyHandler()
+	exportHandler := handlers.NewExportHandler(config)
+	auditHandler := handlers.NewAuditHandler()
+	sessionHandler := handlers.NewSessionHandler(geo)
+	searchHandler := handlers.NewSearchHandler(search.NewPostgresEngine(db, config.SearchLanguage))
 
 	// Set up router
 	router := gin.Default()
+	router.Use(middleware.RequestID())
 
-	// CORS middleware
-	router.Use(func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
-
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
-		}
+	// CORS. The public keys and health check may be read from any origin,
+	// and admin routes can be limited to a separate set of origins.
+	corsPolicy := middleware.CORSPolicyFromConfig(config)
+	publicPolicy := corsPolicy
+	publicPolicy.AllowedOrigins = []string{"*"}
+	publicPolicy.AllowCredentials = false
+	cors := middleware.NewCORS(corsPolicy).
+		Override("/health", publicPolicy).
+		Override("/.well-known", publicPolicy)
+	if len(config.CORSAdminAllowedOrigins) > 0 {
+		adminPolicy := corsPolicy
+		adminPolicy.AllowedOrigins = config.CORSAdminAllowedOrigins
+		cors.Override("/api/admin", adminPolicy)
+	}
+	router.Use(cors.Handler())
 
-		c.Next()
-	})
+	// Double-submit CSRF check for requests authenticated by cookie
+	router.Use(middleware.CSRF(config))
 
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
@@ -61,6 +191,9 @@ func main() {
 		})
 	})
 
+	// Public keys for verifying issued tokens
+	router.GET("/.well-known/jwks.json", keyHandler.GetJWKS)
+
 	// API routes
 	api := router.Group("/api")
 	{
@@ -69,6 +202,18 @@ func main() {
 		{
 			auth.POST("/register", userHandler.Register)
 			auth.POST("/login", userHandler.Login)
+			auth.POST("/mfa", userHandler.VerifyMFA)
+			auth.POST("/magic-link", magicLinkHandler.RequestLink)
+			auth.POST("/magic-link/verify", magicLinkHandler.VerifyLink)
+			auth.GET("/oidc/providers", oidcHandler.GetProviders)
+			auth.GET("/oidc/:provider/login", oidcHandler.Login)
+			auth.GET("/oidc/:provider/callback", oidcHandler.Callback)
+			auth.POST("/refresh", userHandler.Refresh)
+			auth.POST("/logout", middleware.AuthMiddleware(config), userHandler.Logout)
+			auth.POST("/forgot-password", userHandler.ForgotPassword)
+			auth.POST("/reset-password", userHandler.ResetPassword)
+			auth.GET("/verify", userHandler.VerifyEmail)
+			auth.POST("/verify/resend", middleware.AuthMiddleware(config), userHandler.ResendVerification)
 		}
 
 		// User routes
@@ -76,20 +221,53 @@ func main() {
 		user.Use(middleware.AuthMiddleware(config))
 		{
 			user.GET("/profile", userHandler.GetProfile)
+			user.PATCH("/profile", middleware.DenyImpersonation(), userHandler.UpdateProfile)
+			user.POST("/password", middleware.DenyImpersonation(), userHandler.ChangePassword)
+			user.DELETE("/me", middleware.DenyImpersonation(), userHandler.DeleteAccount)
+			user.POST("/me/export", exportHandler.RequestExport)
+			user.GET("/me/exports", exportHandler.GetExports)
+			user.GET("/sessions", sessionHandler.GetSessions)
+			user.DELETE("/sessions", middleware.DenyImpersonation(), sessionHandler.RevokeAllSessions)
+			user.DELETE("/sessions/:id", middleware.DenyImpersonation(), sessionHandler.RevokeSession)
+			user.POST("/mfa/enroll", middleware.DenyImpersonation(), mfaHandler.Enroll)
+			user.POST("/mfa/verify", middleware.DenyImpersonation(), mfaHandler.Verify)
+			user.POST("/mfa/recovery-codes", middleware.DenyImpersonation(), mfaHandler.RegenerateRecoveryCodes)
+			user.POST("/mfa/disable", middleware.DenyImpersonation(), mfaHandler.Disable)
 		}
 
 		// Product routes
 		products := api.Group("/products")
 		{
 			products.GET("", productHandler.GetProducts)
+			products.GET("/search", searchHandler.SearchProducts)
 			products.GET("/:id", productHandler.GetProduct)
+			products.GET("/:id/breadcrumbs", categoryHandler.GetProductBreadcrumbs)
+			products.GET("/:id/variants", variantHandler.GetVariants)
 
-			// Admin only routes
-			products.Use(middleware.AuthMiddleware(config), middleware.AdminMiddleware())
+			// Catalog management routes
+			products.Use(middleware.AuthMiddleware(config), middleware.AdminMFAMiddleware(config), middleware.RequirePermission(rbac.CatalogWrite))
 			{
 				products.POST("", productHandler.CreateProduct)
 				products.PUT("/:id", productHandler.UpdateProduct)
 				products.DELETE("/:id", productHandler.DeleteProduct)
+				products.POST("/:id/variants/generate", variantHandler.GenerateVariants)
+				products.PUT("/:id/variants/:variantId", variantHandler.UpdateVariant)
+			}
+		}
+
+		// Category routes
+		categories := api.Group("/categories")
+		{
+			categories.GET("", categoryHandler.GetTree)
+			categories.GET("/:id", categoryHandler.GetCategory)
+
+			// Catalog management routes
+			categories.Use(middleware.AuthMiddleware(config), middleware.AdminMFAMiddleware(config), middleware.RequirePermission(rbac.CatalogWrite))
+			{
+				categories.POST("", categoryHandler.CreateCategory)
+				categories.POST("/reorder", categoryHandler.ReorderCategories)
+				categories.PUT("/:id", categoryHandler.UpdateCategory)
+				categories.DELETE("/:id", categoryHandler.DeleteCategory)
 			}
 		}
 
@@ -104,15 +282,49 @@ func main() {
 
 		// Payment routes
 		payments := api.Group("/payments")
-		payments.Use(middleware.AuthMiddleware(config))
+		payments.Use(middleware.AuthMiddleware(config), middleware.DenyImpersonation())
 		{
 			payments.POST("/create-intent", paymentHandler.CreatePaymentIntent)
 			payments.POST("/confirm", paymentHandler.ConfirmPayment)
 			payments.GET("/:id", paymentHandler.GetPaymentStatus)
 		}
+
+		// Data export downloads, authorized by the link's token
+		api.GET("/exports/download", exportHandler.Download)
+
+		// Admin routes
+		admin := api.Group("/admin")
+		admin.Use(middleware.AuthMiddleware(config), middleware.AdminMFAMiddleware(config))
+		{
+			admin.GET("/users", middleware.RequirePermission(rbac.UsersRead), adminHandler.GetUsers)
+			admin.GET("/users/:id", middleware.RequirePermission(rbac.UsersRead), adminHandler.GetUser)
+			admin.PUT("/users/:id/role", middleware.RequirePermission(rbac.UsersManage, rbac.RolesManage), adminHandler.UpdateUserRole)
+			admin.POST("/users/:id/disable", middleware.RequirePermission(rbac.UsersManage), adminHandler.DisableUser)
+			admin.POST("/users/:id/enable", middleware.RequirePermission(rbac.UsersManage), adminHandler.EnableUser)
+			admin.POST("/users/:id/logout", middleware.RequirePermission(rbac.UsersManage), adminHandler.LogoutUser)
+			admin.POST("/users/:id/unlock", middleware.RequirePermission(rbac.UsersManage), adminHandler.UnlockUser)
+			admin.POST("/users/:id/impersonate", middleware.RequirePermission(rbac.UsersImpersonate), adminHandler.Impersonate)
+			admin.POST("/users/:id/export", middleware.RequirePermission(rbac.UsersRead), exportHandler.RequestUserExport)
+
+			admin.GET("/orders", middleware.RequirePermission(rbac.OrdersRead), orderHandler.ListAllOrders)
+			admin.GET("/orders/:id", middleware.RequirePermission(rbac.OrdersRead), orderHandler.GetAnyOrder)
+
+			admin.GET("/permissions", middleware.RequirePermission(rbac.RolesManage), roleHandler.GetPermissions)
+			admin.GET("/roles", middleware.RequirePermission(rbac.RolesManage), roleHandler.GetRoles)
+			admin.POST("/roles", middleware.RequirePermission(rbac.RolesManage), roleHandler.CreateRole)
+			admin.PUT("/roles/:id", middleware.RequirePermission(rbac.RolesManage), roleHandler.UpdateRole)
+			admin.DELETE("/roles/:id", middleware.RequirePermission(rbac.RolesManage), roleHandler.DeleteRole)
+
+			admin.GET("/audit-events", middleware.RequirePermission(rbac.AuditRead), auditHandler.GetEvents)
+			admin.GET("/audit-events/verify", middleware.RequirePermission(rbac.AuditRead), auditHandler.VerifyChain)
+
+			admin.GET("/api-keys", middleware.RequirePermission(rbac.APIKeysManage), apiKeyHandler.GetAPIKeys)
+			admin.POST("/api-keys", middleware.RequirePermission(rbac.APIKeysManage), apiKeyHandler.CreateAPIKey)
+			admin.DELETE("/api-keys/:id", middleware.RequirePermission(rbac.APIKeysManage), apiKeyHandler.RevokeAPIKey)
+		}
 	}
 
 	// Start server
 	log.Printf("Server starting on port %s", config.ServerPort)
 	router.Run(":" + config.ServerPort)
-}
\ No newline at end of file
+}
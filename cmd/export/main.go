@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/yourusername/ecommerce/configs"
+	"github.com/yourusername/ecommerce/internal/account"
+	"github.com/yourusername/ecommerce/internal/database"
+)
+
+// export writes the personal data archive for one user to a file, for
+// answering subject access requests from the command line:
+//
+//	go run ./cmd/export -user 42 -out user-42.zip
+func main() {
+	userID := flag.Uint("user", 0, "ID of the user to export")
+	out := flag.String("out", "", "file to write the zip archive to")
+	flag.Parse()
+
+	if *userID == 0 || *out == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	config := configs.LoadConfig()
+	database.Initialize(config)
+
+	bundle, err := account.BuildBundle(*userID)
+	if err != nil {
+		log.Fatalf("Failed to collect data for user %d: %v", *userID, err)
+	}
+
+	file, err := os.OpenFile(*out, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		log.Fatalf("Failed to create %s: %v", *out, err)
+	}
+	if err := account.WriteArchive(file, bundle); err != nil {
+		file.Close()
+		log.Fatalf("Failed to write archive: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		log.Fatalf("Failed to write archive: %v", err)
+	}
+
+	log.Printf("Wrote export of user %d to %s", *userID, *out)
+}
@@ -3,10 +3,23 @@ package configs
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// OIDCProvider holds the settings for one OpenID Connect login provider
+type OIDCProvider struct {
+	Name         string
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
 // Config holds all configuration for the application
 type Config struct {
 	DBHost     string
@@ -14,11 +27,123 @@ type Config struct {
 	DBUser     string
 	DBPassword string
 	DBName     string
-	JWTSecret  string
 	ServerPort string
 	StripeKey  string
+
+	// Token lifetimes
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+
+	// JWT signing keys
+	JWTAlgorithm    string        // RS256 or EdDSA, used for newly generated keys
+	JWTKeyDir       string        // directory of PEM encoded signing keys
+	JWTKeyRotation  time.Duration // age after which a new signing key is generated
+	JWTKeyRetention time.Duration // how long a retired key is still accepted
+	JWTIssuer       string
+	JWTAudience     string
+
+	// Outgoing email
+	AppBaseURL       string // base URL used for links in emails
+	MailDriver       string // smtp or log
+	MailFrom         string
+	MailLogPath      string // file the log driver appends to; stdout log if empty
+	SMTPHost         string
+	SMTPPort         string
+	SMTPUsername     string
+	SMTPPassword     string
+	PasswordResetTTL time.Duration
+
+	// Email verification
+	EmailVerificationTTL          time.Duration
+	RequireVerifiedEmailForOrders bool
+
+	// Passwordless login links
+	MagicLinkTTL       time.Duration
+	MagicLinkMaxEmails int // links sent to one email per window
+	MagicLinkWindow    time.Duration
+
+	// Browser sessions kept in HttpOnly cookies instead of the Authorization
+	// header, with double-submit CSRF protection
+	CookieAuth     bool
+	CookieDomain   string
+	CookieSecure   bool
+	CookieSameSite string // lax, strict or none
+
+	// Cross-origin requests. Origins may be exact (https://shop.example.com),
+	// wildcard subdomains (https://*.example.com) or *, which never gets credentials.
+	CORSAllowedOrigins      []string
+	CORSAdminAllowedOrigins []string // replaces CORSAllowedOrigins for /api/admin if set
+	CORSAllowCredentials    bool
+	CORSAllowedHeaders      []string
+	CORSExposedHeaders      []string
+	CORSMaxAge              time.Duration // preflight cache lifetime
+
+	// Two-factor authentication
+	MFAIssuer       string // name shown in authenticator apps
+	MFAPendingTTL   time.Duration
+	RequireAdminMFA bool
+
+	// Password policy
+	PasswordMinLength          int
+	PasswordMaxLength          int
+	PasswordMinEntropy         int // estimated bits of entropy
+	PasswordRejectEmailDerived bool
+	BreachedPasswordsFile      string // SHA-1 hashes of breached passwords; screening is off if empty
+
+	// Password hashing. Stored hashes made with another scheme or a lower
+	// cost are upgraded when the user next logs in.
+	PasswordHashScheme string // argon2id or bcrypt
+	Argon2Memory       int    // KiB
+	Argon2Iterations   int
+	Argon2Parallelism  int
+	BcryptCost         int
+
+	// Existing account promoted to admin on startup, for bootstrapping
+	BootstrapAdminEmail string
+
+	// Lifetime of the token an admin gets when impersonating a customer
+	ImpersonationTTL time.Duration
+
+	// OpenID Connect login providers, configured with OIDC_PROVIDERS and
+	// OIDC_<NAME>_ISSUER, _CLIENT_ID, _CLIENT_SECRET, _REDIRECT_URL and _SCOPES
+	OIDCProviders []OIDCProvider
+	OIDCStateTTL  time.Duration
+
+	// Login throttling
+	LoginThrottleStore      string        // memory or postgres
+	LoginFailureWindow      time.Duration // failures older than this are forgotten
+	LoginBackoffBase        time.Duration
+	LoginBackoffMax         time.Duration
+	AccountFreeAttempts     int
+	AccountLockoutThreshold int
+	AccountLockoutDuration  time.Duration
+	IPFreeAttempts          int
+	IPLockoutThreshold      int
+	IPLockoutDuration       time.Duration
+
+	// Text search configuration used to stem product text, e.g. english or german
+	SearchLanguage string
+
+	// Key pagination cursors are signed with; a random one is used per process if empty
+	CursorSecret string
+
+	// GeoIP database used to show where sessions are; locations are left out if empty
+	GeoIPFile string // CSV of network,country,region,city
+
+	// Personal data exports
+	ExportDir           string        // directory the export archives are written to
+	ExportLinkTTL       time.Duration // how long a download link stays valid
+	ExportMaxRequests   int           // exports a user may request of their own data per window
+	ExportRequestWindow time.Duration
 }
 
+// Request headers browsers may send cross-origin, and response headers
+// scripts may read, unless overridden
+var (
+	defaultCORSAllowedHeaders = []string{"Accept", "Authorization", "Cache-Control", "Content-Type", "X-CSRF-Token", "X-Request-ID", "X-Requested-With"}
+	defaultCORSExposedHeaders = []string{"X-Request-ID", "Link", "X-Total-Count"}
+)
+
 // LoadConfig loads configuration from environment variables
 func LoadConfig() *Config {
 	err := godotenv.Load()
@@ -26,16 +151,128 @@ func LoadConfig() *Config {
 		log.Println("Warning: .env file not found, using environment variables")
 	}
 
+	if os.Getenv("JWT_SECRET") != "" {
+		log.Println("Warning: JWT_SECRET is no longer used; access tokens are signed with the keys in JWT_KEY_DIR")
+	}
+
+	appBaseURL := getEnv("APP_BASE_URL", "http://localhost:8080")
+
 	return &Config{
 		DBHost:     getEnv("DB_HOST", "localhost"),
 		DBPort:     getEnv("DB_PORT", "5432"),
 		DBUser:     getEnv("DB_USER", "postgres"),
 		DBPassword: getEnv("DB_PASSWORD", "postgres"),
 		DBName:     getEnv("DB_NAME", "ecommerce"),
-		JWTSecret:  getEnv("JWT_SECRET", "your-secret-key"),
 		ServerPort: getEnv("SERVER_PORT", "8080"),
 		StripeKey:  getEnv("STRIPE_KEY", ""),
+
+		AccessTokenTTL:  getEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL: getEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+
+		JWTAlgorithm:    getEnv("JWT_ALGORITHM", "RS256"),
+		JWTKeyDir:       getEnv("JWT_KEY_DIR", "keys"),
+		JWTKeyRotation:  getEnvDuration("JWT_KEY_ROTATION", 30*24*time.Hour),
+		JWTKeyRetention: getEnvDuration("JWT_KEY_RETENTION", 7*24*time.Hour),
+		JWTIssuer:       getEnv("JWT_ISSUER", "ecommerce-api"),
+		JWTAudience:     getEnv("JWT_AUDIENCE", "ecommerce-api"),
+
+		AppBaseURL:       appBaseURL,
+		MailDriver:       getEnv("MAIL_DRIVER", "log"),
+		MailFrom:         getEnv("MAIL_FROM", "no-reply@example.com"),
+		MailLogPath:      getEnv("MAIL_LOG_PATH", ""),
+		SMTPHost:         getEnv("SMTP_HOST", "localhost"),
+		SMTPPort:         getEnv("SMTP_PORT", "587"),
+		SMTPUsername:     getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:     getEnv("SMTP_PASSWORD", ""),
+		PasswordResetTTL: getEnvDuration("PASSWORD_RESET_TTL", time.Hour),
+
+		EmailVerificationTTL:          getEnvDuration("EMAIL_VERIFICATION_TTL", 48*time.Hour),
+		RequireVerifiedEmailForOrders: getEnvBool("REQUIRE_VERIFIED_EMAIL_FOR_ORDERS", false),
+
+		MagicLinkTTL:       getEnvDuration("MAGIC_LINK_TTL", 15*time.Minute),
+		MagicLinkMaxEmails: getEnvInt("MAGIC_LINK_MAX_EMAILS", 3),
+		MagicLinkWindow:    getEnvDuration("MAGIC_LINK_WINDOW", 15*time.Minute),
+
+		CookieAuth:     getEnvBool("COOKIE_AUTH", false),
+		CookieDomain:   getEnv("COOKIE_DOMAIN", ""),
+		CookieSecure:   getEnvBool("COOKIE_SECURE", true),
+		CookieSameSite: getEnv("COOKIE_SAMESITE", "lax"),
+
+		CORSAllowedOrigins:      getEnvList("CORS_ALLOWED_ORIGINS", []string{"*"}),
+		CORSAdminAllowedOrigins: getEnvList("CORS_ADMIN_ALLOWED_ORIGINS", nil),
+		CORSAllowCredentials:    getEnvBool("CORS_ALLOW_CREDENTIALS", true),
+		CORSAllowedHeaders:      getEnvList("CORS_ALLOWED_HEADERS", defaultCORSAllowedHeaders),
+		CORSExposedHeaders:      getEnvList("CORS_EXPOSED_HEADERS", defaultCORSExposedHeaders),
+		CORSMaxAge:              getEnvDuration("CORS_MAX_AGE", 10*time.Minute),
+
+		MFAIssuer:       getEnv("MFA_ISSUER", "E-commerce"),
+		MFAPendingTTL:   getEnvDuration("MFA_PENDING_TTL", 5*time.Minute),
+		RequireAdminMFA: getEnvBool("REQUIRE_ADMIN_MFA", true),
+
+		PasswordMinLength:          getEnvInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordMaxLength:          getEnvInt("PASSWORD_MAX_LENGTH", 128),
+		PasswordMinEntropy:         getEnvInt("PASSWORD_MIN_ENTROPY", 35),
+		PasswordRejectEmailDerived: getEnvBool("PASSWORD_REJECT_EMAIL_DERIVED", true),
+		BreachedPasswordsFile:      getEnv("BREACHED_PASSWORDS_FILE", ""),
+
+		PasswordHashScheme: getEnv("PASSWORD_HASH_SCHEME", "argon2id"),
+		Argon2Memory:       getEnvInt("ARGON2_MEMORY", 64*1024),
+		Argon2Iterations:   getEnvInt("ARGON2_ITERATIONS", 3),
+		Argon2Parallelism:  getEnvInt("ARGON2_PARALLELISM", 2),
+		BcryptCost:         getEnvInt("BCRYPT_COST", 12),
+
+		BootstrapAdminEmail: getEnv("BOOTSTRAP_ADMIN_EMAIL", ""),
+
+		ImpersonationTTL: getEnvDuration("IMPERSONATION_TTL", 15*time.Minute),
+
+		OIDCProviders: loadOIDCProviders(appBaseURL),
+		OIDCStateTTL:  getEnvDuration("OIDC_STATE_TTL", 10*time.Minute),
+
+		LoginThrottleStore:      getEnv("LOGIN_THROTTLE_STORE", "memory"),
+		LoginFailureWindow:      getEnvDuration("LOGIN_FAILURE_WINDOW", time.Hour),
+		LoginBackoffBase:        getEnvDuration("LOGIN_BACKOFF_BASE", time.Second),
+		LoginBackoffMax:         getEnvDuration("LOGIN_BACKOFF_MAX", time.Minute),
+		AccountFreeAttempts:     getEnvInt("ACCOUNT_FREE_ATTEMPTS", 3),
+		AccountLockoutThreshold: getEnvInt("ACCOUNT_LOCKOUT_THRESHOLD", 10),
+		AccountLockoutDuration:  getEnvDuration("ACCOUNT_LOCKOUT_DURATION", 15*time.Minute),
+		IPFreeAttempts:          getEnvInt("IP_FREE_ATTEMPTS", 20),
+		IPLockoutThreshold:      getEnvInt("IP_LOCKOUT_THRESHOLD", 100),
+		IPLockoutDuration:       getEnvDuration("IP_LOCKOUT_DURATION", 15*time.Minute),
+
+		SearchLanguage: getEnv("SEARCH_LANGUAGE", "english"),
+
+		CursorSecret: getEnv("CURSOR_SECRET", ""),
+
+		GeoIPFile: getEnv("GEOIP_FILE", ""),
+
+		ExportDir:           getEnv("EXPORT_DIR", "exports"),
+		ExportLinkTTL:       getEnvDuration("EXPORT_LINK_TTL", 24*time.Hour),
+		ExportMaxRequests:   getEnvInt("EXPORT_MAX_REQUESTS", 3),
+		ExportRequestWindow: getEnvDuration("EXPORT_REQUEST_WINDOW", 24*time.Hour),
+	}
+}
+
+// loadOIDCProviders reads the provider list from OIDC_PROVIDERS and the
+// per-provider OIDC_<NAME>_* variables
+func loadOIDCProviders(appBaseURL string) []OIDCProvider {
+	var providers []OIDCProvider
+	for _, name := range strings.Split(getEnv("OIDC_PROVIDERS", ""), ",") {
+		name = strings.TrimSpace(strings.ToLower(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		providers = append(providers, OIDCProvider{
+			Name:         name,
+			IssuerURL:    getEnv(prefix+"ISSUER", ""),
+			ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			RedirectURL:  getEnv(prefix+"REDIRECT_URL", appBaseURL+"/api/auth/oidc/"+name+"/callback"),
+			Scopes:       strings.Fields(getEnv(prefix+"SCOPES", "openid email profile")),
+		})
 	}
+	return providers
 }
 
 // Helper function to get environment variable with a default value
@@ -45,4 +282,61 @@ func getEnv(key, defaultValue string) string {
 		return defaultValue
 	}
 	return value
-}
\ No newline at end of file
+}
+
+// Helper function to get a duration (e.g. "15m", "720h") from the environment
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Warning: invalid duration for %s, using default %s", key, defaultValue)
+		return defaultValue
+	}
+	return duration
+}
+
+// Helper function to get a boolean from the environment
+func getEnvBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Warning: invalid boolean for %s, using default %t", key, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// Helper function to get an integer from the environment
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Warning: invalid integer for %s, using default %d", key, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// Helper function to get a comma-separated list from the environment
+func getEnvList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	var values []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	return values
+}
@@ -0,0 +1,72 @@
+package account
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/yourusername/ecommerce/internal/auth"
+	"github.com/yourusername/ecommerce/internal/database"
+	"github.com/yourusername/ecommerce/internal/models"
+	"github.com/yourusername/ecommerce/internal/password"
+	"gorm.io/gorm"
+)
+
+// Anonymize deletes a user's account by scrubbing their personal data. The
+// user row and their orders are kept so order history still adds up for
+// accounting, but nothing left identifies the person and the account can no
+// longer be used.
+func Anonymize(userID uint) error {
+	// Nobody knows this password, so the account can't be logged into
+	rawPassword, err := auth.NewOpaqueToken()
+	if err != nil {
+		return err
+	}
+	unusablePassword, err := password.Hash(rawPassword)
+	if err != nil {
+		return err
+	}
+
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"email":             fmt.Sprintf("deleted-%d@deleted.invalid", userID),
+			"password":          unusablePassword,
+			"first_name":        "",
+			"last_name":         "",
+			"disabled":          true,
+			"email_verified_at": nil,
+			"totp_secret":       "",
+			"totp_enabled_at":   nil,
+			"anonymized_at":     now,
+		}).Error; err != nil {
+			return err
+		}
+
+		// Street address and phone number identify the customer; the region
+		// is kept because it decides the tax charged on past orders
+		shippingInfoIDs := tx.Model(&models.Order{}).Where("user_id = ?", userID).Select("shipping_info_id")
+		if err := tx.Model(&models.ShippingInfo{}).Where("id IN (?)", shippingInfoIDs).
+			Updates(map[string]interface{}{"address": "", "phone_number": ""}).Error; err != nil {
+			return err
+		}
+
+		for _, model := range []interface{}{&models.UserIdentity{}, &models.RecoveryCode{}, &models.PasswordResetToken{}, &models.Session{}} {
+			if err := tx.Where("user_id = ?", userID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(&models.APIKey{}).Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", now).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := deleteExports(userID); err != nil {
+		return err
+	}
+
+	return auth.RevokeUserTokens(userID)
+}
@@ -0,0 +1,328 @@
+package account
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/yourusername/ecommerce/configs"
+	"github.com/yourusername/ecommerce/internal/auth"
+	"github.com/yourusername/ecommerce/internal/database"
+	"github.com/yourusername/ecommerce/internal/mailer"
+	"github.com/yourusername/ecommerce/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Export statuses
+const (
+	ExportPending = "pending"
+	ExportReady   = "ready"
+	ExportFailed  = "failed"
+	ExportExpired = "expired"
+)
+
+// ErrInvalidExportToken is returned for unknown or expired download links
+var ErrInvalidExportToken = errors.New("invalid export token")
+
+// Bundle is the machine-readable copy of everything stored about a user
+type Bundle struct {
+	GeneratedAt    time.Time                   `json:"generated_at"`
+	User           models.User                 `json:"user"`
+	Orders         []exportedOrder             `json:"orders"`
+	Payments       []PaymentReference          `json:"payments"`
+	Identities     []models.UserIdentity       `json:"identities"`
+	Sessions       []models.Session            `json:"sessions"`
+	PasswordResets []models.PasswordResetToken `json:"password_resets"`
+	APIKeys        []models.APIKey             `json:"api_keys"`
+	Exports        []models.DataExport         `json:"exports"`
+	AuditEvents    []models.AuditEvent         `json:"audit_events"`
+}
+
+// exportedOrder leaves out the embedded user, which is already at the top of the bundle
+type exportedOrder struct {
+	models.Order
+	User *models.User `json:"user,omitempty"`
+}
+
+// PaymentReference ties an order to the payment taken for it at the provider
+type PaymentReference struct {
+	OrderID   uint    `json:"order_id"`
+	PaymentID string  `json:"payment_id"`
+	Amount    float64 `json:"amount"`
+	Status    string  `json:"status"`
+}
+
+// BuildBundle collects everything tied to a user ID
+func BuildBundle(userID uint) (*Bundle, error) {
+	db := database.GetDB()
+	bundle := &Bundle{GeneratedAt: time.Now()}
+
+	if err := db.First(&bundle.User, userID).Error; err != nil {
+		return nil, err
+	}
+
+	var orders []models.Order
+	if err := db.Preload("OrderItems.Product").Preload("ShippingInfo").
+		Where("user_id = ?", userID).Order("created_at").Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	bundle.Orders = make([]exportedOrder, 0, len(orders))
+	bundle.Payments = []PaymentReference{}
+	for _, order := range orders {
+		bundle.Orders = append(bundle.Orders, exportedOrder{Order: order})
+		if order.PaymentID != "" {
+			bundle.Payments = append(bundle.Payments, PaymentReference{
+				OrderID:   order.ID,
+				PaymentID: order.PaymentID,
+				Amount:    order.TotalAmount,
+				Status:    order.Status,
+			})
+		}
+	}
+
+	lists := []interface{}{&bundle.Identities, &bundle.Sessions, &bundle.PasswordResets, &bundle.Exports}
+	for _, list := range lists {
+		if err := db.Where("user_id = ?", userID).Order("created_at").Find(list).Error; err != nil {
+			return nil, err
+		}
+	}
+	if err := db.Preload("Scopes").Where("user_id = ?", userID).Order("created_at").Find(&bundle.APIKeys).Error; err != nil {
+		return nil, err
+	}
+
+	// Events the user performed or that were performed on their account
+	if err := db.Where("actor_id = ? OR (target_type = ? AND target_id = ?)", userID, "user", strconv.FormatUint(uint64(userID), 10)).
+		Order("id").Find(&bundle.AuditEvents).Error; err != nil {
+		return nil, err
+	}
+
+	return bundle, nil
+}
+
+// WriteArchive writes a bundle as export.json inside a zip archive
+func WriteArchive(w io.Writer, bundle *Bundle) error {
+	archive := zip.NewWriter(w)
+
+	file, err := archive.Create("export.json")
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(bundle); err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+// Exporter builds export archives in the background and emails a download
+// link to whoever asked for them
+type Exporter struct {
+	config *configs.Config
+	mailer mailer.Mailer
+}
+
+// NewExporter creates a new exporter
+func NewExporter(config *configs.Config) *Exporter {
+	return &Exporter{
+		config: config,
+		mailer: mailer.NewMailer(config),
+	}
+}
+
+// exportStaleAfter is how long an export may stay pending before it is taken
+// to be lost, for example to a restart while it was being built
+const exportStaleAfter = time.Hour
+
+// RequestExport records an export of the user's data and starts building it.
+// While an export of the user is still being built that export is returned
+// instead, with started false, so repeated requests don't build it again.
+func (e *Exporter) RequestExport(userID, requestedByID uint) (export *models.DataExport, started bool, err error) {
+	var pending models.DataExport
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		// Lock the user so concurrent requests can't both find nothing pending
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&user, userID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.DataExport{}).
+			Where("user_id = ? AND status = ? AND created_at < ?", userID, ExportPending, time.Now().Add(-exportStaleAfter)).
+			Updates(map[string]interface{}{
+				"status": ExportFailed,
+				"error":  "Export did not finish",
+			}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Where("user_id = ? AND status = ?", userID, ExportPending).Order("created_at DESC").Limit(1).Find(&pending)
+		if result.Error != nil || result.RowsAffected > 0 {
+			return result.Error
+		}
+
+		pending = models.DataExport{
+			UserID:        userID,
+			RequestedByID: requestedByID,
+			Status:        ExportPending,
+		}
+		started = true
+		return tx.Create(&pending).Error
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	if started {
+		go e.run(pending)
+	}
+
+	return &pending, started, nil
+}
+
+// Wait returns how long until the user may request another export of their
+// own data. Exports admins request for the user don't count.
+func (e *Exporter) Wait(userID uint) (time.Duration, error) {
+	limit := max(e.config.ExportMaxRequests, 1)
+	window := e.config.ExportRequestWindow
+
+	var recent []time.Time
+	if err := database.GetDB().Model(&models.DataExport{}).
+		Where("user_id = ? AND requested_by_id = ? AND created_at > ?", userID, userID, time.Now().Add(-window)).
+		Order("created_at DESC").Limit(limit).Pluck("created_at", &recent).Error; err != nil {
+		return 0, err
+	}
+	if len(recent) < limit {
+		return 0, nil
+	}
+
+	// Another request is allowed once the oldest of the last few leaves the window
+	return time.Until(recent[limit-1].Add(window)), nil
+}
+
+// run builds the archive and sends the link, recording a failure on the export
+func (e *Exporter) run(export models.DataExport) {
+	if err := e.build(&export); err != nil {
+		log.Printf("Failed to build export %d: %v", export.ID, err)
+		database.GetDB().Model(&export).Updates(map[string]interface{}{
+			"status": ExportFailed,
+			"error":  "Failed to build export",
+		})
+	}
+}
+
+func (e *Exporter) build(export *models.DataExport) error {
+	bundle, err := BuildBundle(export.UserID)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(e.config.ExportDir, 0700); err != nil {
+		return err
+	}
+	path := filepath.Join(e.config.ExportDir, fmt.Sprintf("export-%d.zip", export.ID))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err := WriteArchive(file, bundle); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	rawToken, err := auth.NewOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(e.config.ExportLinkTTL)
+	if err := database.GetDB().Model(export).Updates(map[string]interface{}{
+		"status":       ExportReady,
+		"file_path":    path,
+		"token_hash":   auth.HashToken(rawToken),
+		"completed_at": now,
+		"expires_at":   expiresAt,
+	}).Error; err != nil {
+		return err
+	}
+
+	var requester models.User
+	if err := database.GetDB().First(&requester, export.RequestedByID).Error; err != nil {
+		return err
+	}
+
+	link := e.config.AppBaseURL + "/api/exports/download?token=" + rawToken
+	return e.mailer.Send(mailer.Message{
+		To:      requester.Email,
+		Subject: "Your data export is ready",
+		Body: "The data export you requested is ready. Download it from the link below. It expires in " + e.config.ExportLinkTTL.String() + ".\n\n" +
+			link,
+	})
+}
+
+// FindDownload returns the ready export a download token belongs to
+func FindDownload(rawToken string) (*models.DataExport, error) {
+	var export models.DataExport
+	result := database.GetDB().Where("token_hash = ? AND status = ?", auth.HashToken(rawToken), ExportReady).Limit(1).Find(&export)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 || export.ExpiresAt == nil || time.Now().After(*export.ExpiresAt) {
+		return nil, ErrInvalidExportToken
+	}
+	return &export, nil
+}
+
+// PurgeExpiredExports deletes archives whose download link has expired
+func PurgeExpiredExports() error {
+	var exports []models.DataExport
+	if err := database.GetDB().Where("status = ? AND expires_at < ?", ExportReady, time.Now()).Find(&exports).Error; err != nil {
+		return err
+	}
+
+	for _, export := range exports {
+		if err := os.Remove(export.FilePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if err := database.GetDB().Model(&export).Updates(map[string]interface{}{
+			"status":     ExportExpired,
+			"file_path":  "",
+			"token_hash": "",
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteExports removes every export archive of a user along with its record
+func deleteExports(userID uint) error {
+	var exports []models.DataExport
+	if err := database.GetDB().Where("user_id = ?", userID).Find(&exports).Error; err != nil {
+		return err
+	}
+
+	for _, export := range exports {
+		if export.FilePath == "" {
+			continue
+		}
+		if err := os.Remove(export.FilePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return database.GetDB().Where("user_id = ?", userID).Delete(&models.DataExport{}).Error
+}
@@ -0,0 +1,215 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/ecommerce/internal/database"
+	"github.com/yourusername/ecommerce/internal/models"
+	"gorm.io/gorm"
+)
+
+// chainLockKey is the advisory lock that serializes appends to the chain
+const chainLockKey = 0x61756469
+
+// errChainBroken stops Verify at the first bad event
+var errChainBroken = errors.New("audit chain broken")
+
+// Event describes an action to record. The request details are filled in by
+// Record, as is the actor unless the handler knows it better, e.g. on login.
+type Event struct {
+	ActorID    *uint
+	Action     string
+	TargetType string
+	TargetID   string
+	Diff       interface{} // anything that marshals to JSON, usually from Changes
+}
+
+// Change is the old and new value of one field
+type Change struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// Record appends an event made during a request. Failures are logged rather
+// than returned so auditing never breaks the action being audited.
+func Record(c *gin.Context, event Event) {
+	entry := models.AuditEvent{
+		Action:     event.Action,
+		TargetType: event.TargetType,
+		TargetID:   event.TargetID,
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+		RequestID:  c.GetString("requestID"),
+	}
+	if event.ActorID != nil {
+		entry.ActorID = event.ActorID
+	} else if userID, ok := c.Get("userID"); ok {
+		if id, ok := userID.(uint); ok {
+			entry.ActorID = &id
+		}
+	}
+
+	if actorID, ok := c.Get("actorID"); ok {
+		if id, ok := actorID.(uint); ok {
+			entry.ImpersonatorID = &id
+		}
+	}
+
+	if event.Diff != nil {
+		diff, err := json.Marshal(event.Diff)
+		if err != nil {
+			log.Printf("Failed to encode audit diff for %s: %v", event.Action, err)
+		} else {
+			entry.Diff = diff
+		}
+	}
+
+	if err := Append(&entry); err != nil {
+		log.Printf("Failed to record audit event %s: %v", event.Action, err)
+	}
+}
+
+// Migrate creates the audit table and a trigger that rejects any update or
+// delete of its rows
+func Migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.AuditEvent{}); err != nil {
+		return err
+	}
+	return db.Exec(`
+		CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'audit_events is append-only';
+		END;
+		$$ LANGUAGE plpgsql;
+
+		DROP TRIGGER IF EXISTS audit_events_append_only ON audit_events;
+		CREATE TRIGGER audit_events_append_only BEFORE UPDATE OR DELETE ON audit_events
+			FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+	`).Error
+}
+
+// Append links an event to the end of the chain and stores it
+func Append(entry *models.AuditEvent) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", chainLockKey).Error; err != nil {
+			return err
+		}
+
+		var last models.AuditEvent
+		if err := tx.Select("hash").Order("id DESC").Limit(1).Find(&last).Error; err != nil {
+			return err
+		}
+
+		// Postgres keeps microseconds; truncate so the hash can be recomputed from the row
+		entry.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+		entry.PrevHash = last.Hash
+		entry.Hash = hashEvent(entry)
+
+		return tx.Create(entry).Error
+	})
+}
+
+// Verify walks the chain in order and returns the ID of the first event whose
+// hash doesn't match its contents or its predecessor, or 0 if the chain is intact
+func Verify() (uint, error) {
+	prevHash := ""
+	var brokenAt uint
+
+	var batch []models.AuditEvent
+	err := database.GetDB().Order("id").FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+		for i := range batch {
+			if batch[i].PrevHash != prevHash || batch[i].Hash != hashEvent(&batch[i]) {
+				brokenAt = batch[i].ID
+				return errChainBroken
+			}
+			prevHash = batch[i].Hash
+		}
+		return nil
+	}).Error
+	if err == errChainBroken {
+		return brokenAt, nil
+	}
+	return 0, err
+}
+
+// Changes returns the fields that differ between two values of the same
+// type, keyed by their JSON names. Either value may be nil for a create or
+// delete, in which case every field of the other is included.
+func Changes(before, after interface{}) map[string]Change {
+	from := toMap(before)
+	to := toMap(after)
+
+	changes := map[string]Change{}
+	for key, value := range to {
+		if old, ok := from[key]; !ok || !reflect.DeepEqual(old, value) {
+			changes[key] = Change{From: from[key], To: value}
+		}
+	}
+	for key, value := range from {
+		if _, ok := to[key]; !ok {
+			changes[key] = Change{From: value}
+		}
+	}
+	// Timestamps change on every save and say nothing about what was done
+	delete(changes, "updated_at")
+	return changes
+}
+
+// ID formats a numeric ID for use as a target ID
+func ID(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+func toMap(value interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	if value == nil {
+		return fields
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fields
+	}
+	json.Unmarshal(encoded, &fields)
+	return fields
+}
+
+// hashEvent hashes an event's contents together with the previous hash
+func hashEvent(entry *models.AuditEvent) string {
+	content, _ := json.Marshal(struct {
+		PrevHash string `json:"prev_hash"`
+		ActorID  *uint  `json:"actor_id"`
+		// Added with impersonation; omitted when empty so older rows still verify
+		ImpersonatorID *uint           `json:"impersonator_id,omitempty"`
+		Action         string          `json:"action"`
+		TargetType     string          `json:"target_type"`
+		TargetID       string          `json:"target_id"`
+		IP             string          `json:"ip"`
+		UserAgent      string          `json:"user_agent"`
+		RequestID      string          `json:"request_id"`
+		Diff           json.RawMessage `json:"diff,omitempty"`
+		CreatedAt      string          `json:"created_at"`
+	}{
+		PrevHash:       entry.PrevHash,
+		ActorID:        entry.ActorID,
+		ImpersonatorID: entry.ImpersonatorID,
+		Action:         entry.Action,
+		TargetType:     entry.TargetType,
+		TargetID:       entry.TargetID,
+		IP:             entry.IP,
+		UserAgent:      entry.UserAgent,
+		RequestID:      entry.RequestID,
+		Diff:           entry.Diff,
+		CreatedAt:      entry.CreatedAt.UTC().Format(time.RFC3339Nano),
+	})
+
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
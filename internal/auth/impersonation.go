@@ -0,0 +1,20 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/yourusername/ecommerce/configs"
+	"github.com/yourusername/ecommerce/internal/models"
+)
+
+// GenerateImpersonationToken issues a short-lived access token for a
+// customer that carries the admin as its actor. No refresh token is issued,
+// so the session ends when the token expires.
+func GenerateImpersonationToken(customer, admin *models.User, config *configs.Config) (string, time.Time, error) {
+	actor := &Actor{
+		UserID: admin.ID,
+		Email:  admin.Email,
+	}
+	token, _, expiresAt, err := signAccessToken(customer.ID, customer.Email, customer.Role, actor, "", config.ImpersonationTTL, config)
+	return token, expiresAt, err
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/yourusername/ecommerce/configs"
+	"github.com/yourusername/ecommerce/internal/rbac"
 )
 
 // Claims represents the JWT claims
@@ -13,37 +14,99 @@ type Claims struct {
 	UserID uint   `json:"user_id"`
 	Email  string `json:"email"`
 	Role   string `json:"role"`
+	// Permissions granted by the role when the token was issued
+	Permissions []string `json:"perms,omitempty"`
+	// Purpose is empty for access tokens and names the single use of any other token
+	Purpose string `json:"purpose,omitempty"`
+	// Actor is the admin acting as the user when the token was issued for impersonation
+	Actor *Actor `json:"act,omitempty"`
+	// SessionID names the server-side session the token was issued for
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken generates a JWT token for a user
+// Actor identifies who is really behind an impersonation token
+type Actor struct {
+	UserID uint   `json:"user_id"`
+	Email  string `json:"email"`
+}
+
+// GenerateToken generates a short-lived JWT access token for a user
 func GenerateToken(userID uint, email, role string, config *configs.Config) (string, error) {
+	tokenString, _, _, err := generateAccessToken(userID, email, role, "", config)
+	return tokenString, err
+}
+
+// generateAccessToken signs an access token and also returns its jti and expiry
+func generateAccessToken(userID uint, email, role, sessionID string, config *configs.Config) (string, string, time.Time, error) {
+	return signAccessToken(userID, email, role, nil, sessionID, config.AccessTokenTTL, config)
+}
+
+// signAccessToken signs an access token with the role's current permissions
+func signAccessToken(userID uint, email, role string, actor *Actor, sessionID string, ttl time.Duration, config *configs.Config) (string, string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	tokenID, err := newTokenID()
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+
+	permissions, err := rbac.PermissionsForRole(role)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+
 	claims := &Claims{
-		UserID: userID,
-		Email:  email,
-		Role:   role,
+		UserID:      userID,
+		Email:       email,
+		Role:        role,
+		Permissions: permissions,
+		Actor:       actor,
+		SessionID:   sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        tokenID,
+			Issuer:    config.JWTIssuer,
+			Audience:  jwt.ClaimStrings{config.JWTAudience},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(config.JWTSecret))
+	tokenString, err := keyRing.sign(claims)
 	if err != nil {
-		return "", err
+		return "", "", time.Time{}, err
 	}
 
-	return tokenString, nil
+	return tokenString, tokenID, expiresAt, nil
 }
 
-// ValidateToken validates a JWT token
+// ValidateToken validates a JWT access token
 func ValidateToken(tokenString string, config *configs.Config) (*Claims, error) {
+	claims, err := parseToken(tokenString, config)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Purpose != "" {
+		return nil, errors.New("not an access token")
+	}
+
+	return claims, nil
+}
+
+// parseToken verifies a token's signature, expiry, issuer and audience
+func parseToken(tokenString string, config *configs.Config) (*Claims, error) {
 	claims := &Claims{}
 
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(config.JWTSecret), nil
-	})
+	if keyRing == nil {
+		return nil, errors.New("key ring not initialized")
+	}
+
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{
+		jwt.SigningMethodRS256.Alg(),
+		jwt.SigningMethodEdDSA.Alg(),
+	}))
+	token, err := parser.ParseWithClaims(tokenString, claims, keyRing.keyFunc)
 
 	if err != nil {
 		return nil, err
@@ -53,5 +116,17 @@ func ValidateToken(tokenString string, config *configs.Config) (*Claims, error)
 		return nil, errors.New("invalid token")
 	}
 
+	if !claims.VerifyIssuer(config.JWTIssuer, true) {
+		return nil, errors.New("invalid token issuer")
+	}
+
+	if !claims.VerifyAudience(config.JWTAudience, true) {
+		return nil, errors.New("invalid token audience")
+	}
+
+	if claims.ID == "" {
+		return nil, errors.New("token has no jti")
+	}
+
 	return claims, nil
-}
\ No newline at end of file
+}
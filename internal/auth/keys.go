@@ -0,0 +1,327 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/yourusername/ecommerce/configs"
+)
+
+// signingKey is a key pair loaded from a PEM file. Keys loaded from a public
+// key file have no private half and are only used for verification.
+type signingKey struct {
+	ID        string
+	Method    jwt.SigningMethod
+	Private   crypto.Signer
+	Public    crypto.PublicKey
+	CreatedAt time.Time
+	RetiredAt *time.Time
+}
+
+// KeyRing holds the keys used to sign and verify tokens. The newest private
+// key signs; older keys stay available for verification until their
+// retention period has passed.
+type KeyRing struct {
+	mu        sync.RWMutex
+	dir       string
+	algorithm string
+	rotation  time.Duration
+	retention time.Duration
+	keys      map[string]*signingKey
+	current   *signingKey
+}
+
+var keyRing *KeyRing
+
+// InitKeyRing loads the signing keys from the configured directory, generating
+// one if none exist, and installs the key ring used by the token functions
+func InitKeyRing(config *configs.Config) error {
+	if config.JWTAlgorithm != "RS256" && config.JWTAlgorithm != "EdDSA" {
+		return fmt.Errorf("unsupported JWT algorithm %q", config.JWTAlgorithm)
+	}
+
+	ring := &KeyRing{
+		dir:       config.JWTKeyDir,
+		algorithm: config.JWTAlgorithm,
+		rotation:  config.JWTKeyRotation,
+		retention: config.JWTKeyRetention,
+	}
+
+	if err := os.MkdirAll(ring.dir, 0700); err != nil {
+		return err
+	}
+	if err := ring.Reload(); err != nil {
+		return err
+	}
+	if ring.signer() == nil {
+		if err := ring.Rotate(); err != nil {
+			return err
+		}
+	}
+
+	keyRing = ring
+	return nil
+}
+
+// GetKeyRing returns the installed key ring
+func GetKeyRing() *KeyRing {
+	return keyRing
+}
+
+// Reload re-reads the key directory
+func (r *KeyRing) Reload() error {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		return err
+	}
+
+	var loaded []*signingKey
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".pem" {
+			continue
+		}
+		key, err := loadKeyFile(filepath.Join(r.dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("loading %s: %w", entry.Name(), err)
+		}
+		loaded = append(loaded, key)
+	}
+
+	// Every private key is retired by the next newer one
+	sort.Slice(loaded, func(i, j int) bool { return loaded[i].CreatedAt.Before(loaded[j].CreatedAt) })
+	var current *signingKey
+	for _, key := range loaded {
+		if key.Private == nil {
+			continue
+		}
+		if current != nil {
+			retiredAt := key.CreatedAt
+			current.RetiredAt = &retiredAt
+		}
+		current = key
+	}
+
+	keys := make(map[string]*signingKey)
+	now := time.Now()
+	for _, key := range loaded {
+		if key.RetiredAt != nil && now.After(key.RetiredAt.Add(r.retention)) {
+			continue
+		}
+		keys[key.ID] = key
+	}
+
+	r.mu.Lock()
+	r.keys = keys
+	r.current = current
+	r.mu.Unlock()
+
+	return nil
+}
+
+// Rotate generates a new signing key, writes it to the key directory and
+// makes it the current signing key. The previous key is retired.
+func (r *KeyRing) Rotate() error {
+	var private crypto.Signer
+	var err error
+	switch r.algorithm {
+	case "EdDSA":
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	}
+	if err != nil {
+		return err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	kid := now.Format(kidTimeLayout) + "-" + hex.EncodeToString(suffix)
+	block := &pem.Block{
+		Type:    "PRIVATE KEY",
+		Headers: map[string]string{createdHeader: now.Format(time.RFC3339)},
+		Bytes:   der,
+	}
+
+	// Write to a temporary file first so a partially written key is never loaded
+	path := filepath.Join(r.dir, kid+".pem")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, pem.EncodeToMemory(block), 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+
+	log.Printf("Generated new %s signing key %s", r.algorithm, kid)
+	return r.Reload()
+}
+
+// StartRotation checks the key ring periodically, picking up keys added by
+// other instances and rotating once the current key is older than the rotation period
+func (r *KeyRing) StartRotation(checkInterval time.Duration) {
+	go func() {
+		for range time.Tick(checkInterval) {
+			if err := r.Reload(); err != nil {
+				log.Printf("Failed to reload signing keys: %v", err)
+				continue
+			}
+			current := r.signer()
+			if current == nil || time.Since(current.CreatedAt) >= r.rotation {
+				if err := r.Rotate(); err != nil {
+					log.Printf("Failed to rotate signing key: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// signer returns the key new tokens are signed with
+func (r *KeyRing) signer() *signingKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current
+}
+
+// verifier returns the key with the given ID, if it is still accepted
+func (r *KeyRing) verifier(kid string) (*signingKey, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	key, ok := r.keys[kid]
+	return key, ok
+}
+
+// publicKeys returns every key that is still accepted, newest first
+func (r *KeyRing) publicKeys() []*signingKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]*signingKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.After(keys[j].CreatedAt) })
+	return keys
+}
+
+// sign signs a set of claims with the current key, setting the kid header
+func (r *KeyRing) sign(claims jwt.Claims) (string, error) {
+	if r == nil {
+		return "", errors.New("key ring not initialized")
+	}
+
+	key := r.signer()
+	if key == nil {
+		return "", errors.New("no signing key available")
+	}
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
+}
+
+// keyFunc resolves the verification key for a token from its kid header
+func (r *KeyRing) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := r.verifier(kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+	return key.Public, nil
+}
+
+// kidTimeLayout is the timestamp that starts the ID of every generated key
+const kidTimeLayout = "20060102T150405"
+
+// createdHeader is the PEM header recording when a key was generated
+const createdHeader = "Created"
+
+// keyCreatedAt returns when a key was created, from its Created PEM header
+// or else the timestamp its ID starts with. File modification times are not
+// used, since copying or restoring the key directory changes them.
+func keyCreatedAt(id string, block *pem.Block) (time.Time, error) {
+	if created, ok := block.Headers[createdHeader]; ok {
+		return time.Parse(time.RFC3339, created)
+	}
+
+	stamp, _, _ := strings.Cut(id, "-")
+	createdAt, err := time.Parse(kidTimeLayout, stamp)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("no creation time: add a %s PEM header or name the file <%s>-<suffix>.pem", createdHeader, kidTimeLayout)
+	}
+	return createdAt, nil
+}
+
+// loadKeyFile parses a PEM encoded private or public key. The key ID is the
+// file name without its extension.
+func loadKeyFile(path string) (*signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	key := &signingKey{ID: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))}
+	if key.CreatedAt, err = keyCreatedAt(key.ID, block); err != nil {
+		return nil, err
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.Private, key.Public = jwt.SigningMethodRS256, k, &k.PublicKey
+	case ed25519.PrivateKey:
+		key.Method, key.Private, key.Public = jwt.SigningMethodEdDSA, k, k.Public()
+	case *rsa.PublicKey:
+		key.Method, key.Public = jwt.SigningMethodRS256, k
+	case ed25519.PublicKey:
+		key.Method, key.Public = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestKeyCreatedAt(t *testing.T) {
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		id      string
+		headers map[string]string
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "kid timestamp", id: "20240301T120000-ab12cd34", want: created},
+		{name: "header wins over kid", id: "20200101T000000-ab12cd34", headers: map[string]string{createdHeader: created.Format(time.RFC3339)}, want: created},
+		{name: "header on custom name", id: "partner-key", headers: map[string]string{createdHeader: created.Format(time.RFC3339)}, want: created},
+		{name: "custom name without header", id: "partner-key", wantErr: true},
+		{name: "malformed header", id: "20240301T120000-ab12cd34", headers: map[string]string{createdHeader: "yesterday"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := keyCreatedAt(tt.id, &pem.Block{Type: "PRIVATE KEY", Headers: tt.headers})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("keyCreatedAt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !got.Equal(tt.want) {
+				t.Errorf("keyCreatedAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReloadIgnoresModTime(t *testing.T) {
+	ring := &KeyRing{dir: t.TempDir(), algorithm: "EdDSA", rotation: time.Hour, retention: time.Hour}
+	if err := ring.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	older := ring.signer()
+
+	// Kids have one-second resolution
+	time.Sleep(1100 * time.Millisecond)
+	if err := ring.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	newer := ring.signer()
+	if newer.ID == older.ID {
+		t.Fatal("rotation did not change the signing key")
+	}
+
+	// Touching the older key, as a copy or restore would, must not make it current again
+	future := time.Now().Add(24 * time.Hour)
+	if err := os.Chtimes(filepath.Join(ring.dir, older.ID+".pem"), future, future); err != nil {
+		t.Fatal(err)
+	}
+	if err := ring.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := ring.signer().ID; got != newer.ID {
+		t.Errorf("signer after touching old key = %s, want %s", got, newer.ID)
+	}
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/yourusername/ecommerce/internal/database"
+	"github.com/yourusername/ecommerce/internal/models"
+	"github.com/yourusername/ecommerce/internal/totp"
+	"gorm.io/gorm"
+)
+
+// RecoveryCodeCount is the number of recovery codes issued at a time
+const RecoveryCodeCount = 10
+
+// ErrInvalidMFACode is returned when neither a valid TOTP code nor an unused recovery code was given
+var ErrInvalidMFACode = errors.New("invalid two-factor code")
+
+// VerifySecondFactor checks a TOTP code, or if none is given consumes a
+// recovery code. Each TOTP code is accepted only once.
+func VerifySecondFactor(user *models.User, code, recoveryCode string) error {
+	if code != "" {
+		step, ok := totp.Validate(user.TOTPSecret, code, time.Now(), user.TOTPLastStep)
+		if !ok {
+			return ErrInvalidMFACode
+		}
+
+		// Only one request may claim a given step
+		result := database.GetDB().Model(&models.User{}).
+			Where("id = ? AND totp_last_step < ?", user.ID, step).
+			Update("totp_last_step", step)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidMFACode
+		}
+		user.TOTPLastStep = step
+		return nil
+	}
+
+	if recoveryCode != "" {
+		result := database.GetDB().Model(&models.RecoveryCode{}).
+			Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, HashToken(normalizeRecoveryCode(recoveryCode))).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	return ErrInvalidMFACode
+}
+
+// GenerateRecoveryCodes replaces a user's recovery codes with a fresh set
+// and returns them. The codes are only ever available in plain text here.
+func GenerateRecoveryCodes(userID uint) ([]string, error) {
+	codes := make([]string, RecoveryCodeCount)
+	records := make([]models.RecoveryCode, RecoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		raw := base32.StdEncoding.EncodeToString(b)
+		codes[i] = raw[0:4] + "-" + raw[4:8] + "-" + raw[8:12] + "-" + raw[12:16]
+		records[i] = models.RecoveryCode{UserID: userID, CodeHash: HashToken(raw)}
+	}
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&records).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// normalizeRecoveryCode strips the separators users may or may not type
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/yourusername/ecommerce/configs"
+	"github.com/yourusername/ecommerce/internal/database"
+	"github.com/yourusername/ecommerce/internal/models"
+	"gorm.io/gorm/clause"
+)
+
+// Token purposes. A purpose token is only accepted by the flow it was issued for.
+const (
+	PurposeEmailVerification = "email_verification"
+	PurposeMFAPending        = "mfa_pending"
+	PurposeMagicLink         = "magic_link"
+)
+
+// GeneratePurposeToken signs a token that can only be used for the given purpose
+func GeneratePurposeToken(purpose string, userID uint, email string, ttl time.Duration, config *configs.Config) (string, error) {
+	now := time.Now()
+	tokenID, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	claims := &Claims{
+		UserID:  userID,
+		Email:   email,
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			Issuer:    config.JWTIssuer,
+			Audience:  jwt.ClaimStrings{config.JWTAudience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+
+	return keyRing.sign(claims)
+}
+
+// ValidatePurposeToken validates a token issued by GeneratePurposeToken for the given purpose
+func ValidatePurposeToken(tokenString, purpose string, config *configs.Config) (*Claims, error) {
+	claims, err := parseToken(tokenString, config)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Purpose != purpose {
+		return nil, errors.New("token was issued for a different purpose")
+	}
+
+	return claims, nil
+}
+
+// ConsumePurposeToken validates a purpose token and marks it used, so that a
+// second attempt with the same token fails
+func ConsumePurposeToken(tokenString, purpose string, config *configs.Config) (*Claims, error) {
+	claims, err := ValidatePurposeToken(tokenString, purpose, config)
+	if err != nil {
+		return nil, err
+	}
+
+	// The revocation list doubles as the record of used tokens
+	result := database.GetDB().Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.RevokedToken{JTI: claims.ID, ExpiresAt: claims.ExpiresAt.Time})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("token has already been used")
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,297 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/yourusername/ecommerce/configs"
+	"github.com/yourusername/ecommerce/internal/database"
+	"github.com/yourusername/ecommerce/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrInvalidRefreshToken is returned for unknown, expired or revoked refresh tokens
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused is returned when an already rotated refresh token is presented again
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+)
+
+// TokenPair is an access token together with the refresh token that renews it
+type TokenPair struct {
+	UserID       uint
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int64 // access token lifetime in seconds
+}
+
+// IssueTokenPair issues an access token and starts a new session with its own
+// refresh token family
+func IssueTokenPair(user *models.User, client ClientInfo, config *configs.Config) (*TokenPair, error) {
+	familyID, err := newTokenID()
+	if err != nil {
+		return nil, err
+	}
+
+	var pair *TokenPair
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		session := models.Session{
+			ID:         familyID,
+			UserID:     user.ID,
+			UserAgent:  client.UserAgent,
+			IP:         client.IP,
+			LastSeenAt: now,
+			ExpiresAt:  now.Add(config.RefreshTokenTTL),
+		}
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+
+		var err error
+		pair, err = issueTokenPair(tx, user, familyID, config)
+		return err
+	})
+	return pair, err
+}
+
+func issueTokenPair(tx *gorm.DB, user *models.User, familyID string, config *configs.Config) (*TokenPair, error) {
+	accessToken, accessTokenID, accessExpiresAt, err := generateAccessToken(user.ID, user.Email, user.Role, familyID, config)
+	if err != nil {
+		return nil, err
+	}
+
+	rawRefreshToken, err := NewOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken := models.RefreshToken{
+		UserID:          user.ID,
+		FamilyID:        familyID,
+		TokenHash:       HashToken(rawRefreshToken),
+		AccessTokenID:   accessTokenID,
+		AccessExpiresAt: accessExpiresAt,
+		ExpiresAt:       time.Now().Add(config.RefreshTokenTTL),
+	}
+	if err := tx.Create(&refreshToken).Error; err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		UserID:       user.ID,
+		AccessToken:  accessToken,
+		RefreshToken: rawRefreshToken,
+		ExpiresIn:    int64(config.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+// RotateRefreshToken exchanges a refresh token for a new token pair in the same
+// family. Presenting a token that has already been rotated revokes the family.
+func RotateRefreshToken(rawRefreshToken string, client ClientInfo, config *configs.Config) (*TokenPair, error) {
+	var pair *TokenPair
+	reused := false
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var refreshToken models.RefreshToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", HashToken(rawRefreshToken)).
+			First(&refreshToken).Error; err != nil {
+			return ErrInvalidRefreshToken
+		}
+
+		if refreshToken.RevokedAt != nil {
+			return ErrInvalidRefreshToken
+		}
+
+		// A used token showing up again means it was copied; kill the family
+		if refreshToken.UsedAt != nil {
+			reused = true
+			return revokeFamily(tx, refreshToken.FamilyID)
+		}
+
+		if time.Now().After(refreshToken.ExpiresAt) {
+			return ErrInvalidRefreshToken
+		}
+
+		if err := tx.Model(&refreshToken).Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		var user models.User
+		if err := tx.First(&user, refreshToken.UserID).Error; err != nil || user.Disabled {
+			return ErrInvalidRefreshToken
+		}
+
+		now := time.Now()
+		if err := tx.Model(&models.Session{}).Where("id = ?", refreshToken.FamilyID).Updates(map[string]interface{}{
+			"user_agent":   client.UserAgent,
+			"ip":           client.IP,
+			"last_seen_at": now,
+			"expires_at":   now.Add(config.RefreshTokenTTL),
+		}).Error; err != nil {
+			return err
+		}
+
+		var err error
+		pair, err = issueTokenPair(tx, &user, refreshToken.FamilyID, config)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		return nil, ErrRefreshTokenReused
+	}
+
+	return pair, nil
+}
+
+// RevokeAccessToken adds an access token's jti to the revocation list
+func RevokeAccessToken(tokenID string, expiresAt time.Time) error {
+	return revokeAccessToken(database.GetDB(), tokenID, expiresAt)
+}
+
+func revokeAccessToken(tx *gorm.DB, tokenID string, expiresAt time.Time) error {
+	if tokenID == "" || time.Now().After(expiresAt) {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.RevokedToken{JTI: tokenID, ExpiresAt: expiresAt}).Error
+}
+
+// IsTokenRevoked reports whether an access token's jti has been revoked
+func IsTokenRevoked(tokenID string) (bool, error) {
+	var count int64
+	err := database.GetDB().Model(&models.RevokedToken{}).Where("jti = ?", tokenID).Count(&count).Error
+	return count > 0, err
+}
+
+// RevokeSessionByAccessToken revokes the refresh token family that an access token was issued with
+func RevokeSessionByAccessToken(tokenID string) error {
+	var refreshToken models.RefreshToken
+	result := database.GetDB().Where("access_token_id = ?", tokenID).Limit(1).Find(&refreshToken)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+	return RevokeFamily(refreshToken.FamilyID)
+}
+
+// RevokeFamily revokes every refresh token in a family along with the access tokens issued with them
+func RevokeFamily(familyID string) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		return revokeFamily(tx, familyID)
+	})
+}
+
+// RevokeUserTokens revokes every refresh token family belonging to a user
+func RevokeUserTokens(userID uint) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var familyIDs []string
+		if err := tx.Model(&models.RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Distinct().Pluck("family_id", &familyIDs).Error; err != nil {
+			return err
+		}
+		for _, familyID := range familyIDs {
+			if err := revokeFamily(tx, familyID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RevokeOtherSessions revokes every refresh token family belonging to a user
+// except the one the given access token was issued with
+func RevokeOtherSessions(userID uint, tokenID string) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var currentFamilyID string
+		if tokenID != "" {
+			var current models.RefreshToken
+			if err := tx.Where("access_token_id = ?", tokenID).Limit(1).Find(&current).Error; err != nil {
+				return err
+			}
+			currentFamilyID = current.FamilyID
+		}
+
+		var familyIDs []string
+		if err := tx.Model(&models.RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL AND family_id <> ?", userID, currentFamilyID).
+			Distinct().Pluck("family_id", &familyIDs).Error; err != nil {
+			return err
+		}
+		for _, familyID := range familyIDs {
+			if err := revokeFamily(tx, familyID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func revokeFamily(tx *gorm.DB, familyID string) error {
+	var refreshTokens []models.RefreshToken
+	if err := tx.Where("family_id = ?", familyID).Find(&refreshTokens).Error; err != nil {
+		return err
+	}
+
+	for _, refreshToken := range refreshTokens {
+		if err := revokeAccessToken(tx, refreshToken.AccessTokenID, refreshToken.AccessExpiresAt); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	if err := tx.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", now).Error
+}
+
+// PurgeExpiredTokens deletes refresh tokens, sessions, revocation entries and
+// pending login states that have expired
+func PurgeExpiredTokens() error {
+	now := time.Now()
+	for _, model := range []interface{}{&models.RefreshToken{}, &models.Session{}, &models.RevokedToken{}, &models.OAuthState{}} {
+		if err := database.GetDB().Where("expires_at < ?", now).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewOpaqueToken returns a random URL-safe token suitable for handing to clients
+func NewOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the hex-encoded SHA-256 of a token, used for storage and lookup
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// newTokenID returns a random identifier used for jti and family IDs
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
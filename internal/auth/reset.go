@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/yourusername/ecommerce/configs"
+	"github.com/yourusername/ecommerce/internal/database"
+	"github.com/yourusername/ecommerce/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidResetToken is returned for unknown, expired or already used reset tokens
+var ErrInvalidResetToken = errors.New("invalid password reset token")
+
+// CreatePasswordResetToken issues a reset token for a user. Any reset tokens
+// the user still had outstanding stop working.
+func CreatePasswordResetToken(userID uint, config *configs.Config) (string, error) {
+	rawToken, err := NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", userID).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.PasswordResetToken{
+			UserID:    userID,
+			TokenHash: HashToken(rawToken),
+			ExpiresAt: time.Now().Add(config.PasswordResetTTL),
+		}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return rawToken, nil
+}
+
+// PasswordResetTokenUser returns the user a reset token belongs to without
+// consuming it, so the new password can be checked against their account
+func PasswordResetTokenUser(rawToken string) (*models.User, error) {
+	var resetToken models.PasswordResetToken
+	result := database.GetDB().Where("token_hash = ?", HashToken(rawToken)).Limit(1).Find(&resetToken)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 || resetToken.UsedAt != nil || time.Now().After(resetToken.ExpiresAt) {
+		return nil, ErrInvalidResetToken
+	}
+
+	var user models.User
+	if err := database.GetDB().First(&user, resetToken.UserID).Error; err != nil {
+		return nil, ErrInvalidResetToken
+	}
+	return &user, nil
+}
+
+// ResetPassword consumes a reset token and sets the user's new password. All
+// of the user's existing sessions are revoked.
+func ResetPassword(rawToken, newPassword string) (*models.User, error) {
+	var user models.User
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var resetToken models.PasswordResetToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", HashToken(rawToken)).
+			First(&resetToken).Error; err != nil {
+			return ErrInvalidResetToken
+		}
+
+		if resetToken.UsedAt != nil || time.Now().After(resetToken.ExpiresAt) {
+			return ErrInvalidResetToken
+		}
+
+		if err := tx.Model(&resetToken).Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		if err := tx.First(&user, resetToken.UserID).Error; err != nil {
+			return ErrInvalidResetToken
+		}
+
+		if err := user.SetPassword(newPassword); err != nil {
+			return err
+		}
+		return tx.Save(&user).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := RevokeUserTokens(user.ID); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
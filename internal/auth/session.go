@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/yourusername/ecommerce/internal/database"
+	"github.com/yourusername/ecommerce/internal/models"
+)
+
+// ClientInfo describes the device a session is started or renewed from
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// sessionActivity is the latest request seen for a session
+type sessionActivity struct {
+	ip     string
+	seenAt time.Time
+}
+
+// Authenticated requests only record activity in memory; it is written to
+// the database in batches by FlushSessionActivity
+var (
+	activityMu      sync.Mutex
+	pendingActivity = map[string]sessionActivity{}
+)
+
+// TouchSession records that a session was just used from an IP address
+func TouchSession(sessionID, ip string) {
+	if sessionID == "" {
+		return
+	}
+	activityMu.Lock()
+	pendingActivity[sessionID] = sessionActivity{ip: ip, seenAt: time.Now()}
+	activityMu.Unlock()
+}
+
+// FlushSessionActivity writes the recorded activity to the sessions table.
+// A failed write doesn't stop the rest of the batch; the activity that
+// couldn't be written is kept for the next flush.
+func FlushSessionActivity() error {
+	activityMu.Lock()
+	activity := pendingActivity
+	pendingActivity = map[string]sessionActivity{}
+	activityMu.Unlock()
+
+	failed := map[string]sessionActivity{}
+	var errs []error
+	for sessionID, seen := range activity {
+		if err := database.GetDB().Model(&models.Session{}).
+			Where("id = ? AND last_seen_at < ?", sessionID, seen.seenAt).
+			Updates(map[string]interface{}{"last_seen_at": seen.seenAt, "ip": seen.ip}).Error; err != nil {
+			failed[sessionID] = seen
+			errs = append(errs, err)
+		}
+	}
+
+	requeueActivity(failed)
+	return errors.Join(errs...)
+}
+
+// requeueActivity puts activity that couldn't be written back in the pending
+// batch, unless the session has been used again since
+func requeueActivity(failed map[string]sessionActivity) {
+	activityMu.Lock()
+	defer activityMu.Unlock()
+
+	for sessionID, seen := range failed {
+		if _, ok := pendingActivity[sessionID]; !ok {
+			pendingActivity[sessionID] = seen
+		}
+	}
+}
+
+// StartSessionActivityFlusher flushes recorded session activity at the given interval
+func StartSessionActivityFlusher(interval time.Duration) {
+	go func() {
+		for range time.Tick(interval) {
+			if err := FlushSessionActivity(); err != nil {
+				log.Printf("Failed to record session activity: %v", err)
+			}
+		}
+	}()
+}
+
+// ActiveSessions returns a user's sessions that have not been revoked or expired, most recently used first
+func ActiveSessions(userID uint) ([]models.Session, error) {
+	sessions := []models.Session{}
+	err := database.GetDB().
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRequeueActivityKeepsNewerActivity(t *testing.T) {
+	activityMu.Lock()
+	pendingActivity = map[string]sessionActivity{}
+	activityMu.Unlock()
+	t.Cleanup(func() {
+		activityMu.Lock()
+		pendingActivity = map[string]sessionActivity{}
+		activityMu.Unlock()
+	})
+
+	earlier := time.Now().Add(-time.Minute)
+	// The session was used again while the failed batch was being written
+	TouchSession("used-again", "10.0.0.2")
+
+	requeueActivity(map[string]sessionActivity{
+		"used-again": {ip: "10.0.0.1", seenAt: earlier},
+		"idle":       {ip: "10.0.0.3", seenAt: earlier},
+	})
+
+	activityMu.Lock()
+	defer activityMu.Unlock()
+
+	if got := pendingActivity["used-again"]; got.ip != "10.0.0.2" || !got.seenAt.After(earlier) {
+		t.Errorf("newer activity was overwritten by the failed write: %+v", got)
+	}
+	if got, ok := pendingActivity["idle"]; !ok || got.ip != "10.0.0.3" || !got.seenAt.Equal(earlier) {
+		t.Errorf("failed write was not kept for the next flush: %+v, %v", got, ok)
+	}
+}
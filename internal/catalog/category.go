@@ -0,0 +1,331 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/yourusername/ecommerce/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrCategoryCycle is returned when a category would be moved under itself or one of its descendants
+	ErrCategoryCycle = errors.New("category cannot be moved under itself")
+	// ErrSlugTaken is returned when another category already uses a slug
+	ErrSlugTaken = errors.New("slug is already in use")
+	// ErrInvalidSlug is returned for slugs that aren't lowercase letters, digits and hyphens
+	ErrInvalidSlug = errors.New("slug may only contain lowercase letters, digits and hyphens")
+	// ErrInvalidOrder is returned when a new order doesn't list each subcategory exactly once
+	ErrInvalidOrder = errors.New("order must list every subcategory exactly once")
+)
+
+// Node is a category with its subcategories, for returning the whole tree
+type Node struct {
+	models.Category
+	Children []*Node `json:"children"`
+}
+
+// Migrate indexes category paths for prefix searches and fills in the path
+// and slug of categories created before they existed
+func Migrate(db *gorm.DB) error {
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_categories_path ON categories (path text_pattern_ops)").Error; err != nil {
+		return err
+	}
+
+	var categories []models.Category
+	if err := db.Order("id").Find(&categories).Error; err != nil {
+		return err
+	}
+	byID := make(map[uint]models.Category, len(categories))
+	for _, category := range categories {
+		byID[category.ID] = category
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, category := range categories {
+			updates := map[string]interface{}{}
+
+			if path := pathFromParents(category, byID); path != category.Path {
+				updates["path"] = path
+			}
+			if category.Slug == "" {
+				slug, err := uniqueSlug(tx, Slugify(category.Name), category.ID)
+				if err != nil {
+					return err
+				}
+				updates["slug"] = slug
+			}
+
+			if len(updates) > 0 {
+				if err := tx.Model(&models.Category{}).Where("id = ?", category.ID).Updates(updates).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// pathFromParents builds a category's path by following parent IDs, stopping
+// at missing parents or cycles left by hand-edited rows
+func pathFromParents(category models.Category, byID map[uint]models.Category) string {
+	ids := []uint{category.ID}
+	seen := map[uint]bool{category.ID: true}
+	for current := category; current.ParentID != nil; {
+		parent, ok := byID[*current.ParentID]
+		if !ok || seen[parent.ID] {
+			break
+		}
+		ids = append([]uint{parent.ID}, ids...)
+		seen[parent.ID] = true
+		current = parent
+	}
+
+	var path strings.Builder
+	path.WriteString("/")
+	for _, id := range ids {
+		path.WriteString(strconv.FormatUint(uint64(id), 10))
+		path.WriteString("/")
+	}
+	return path.String()
+}
+
+// Slugify turns a name into a URL-friendly slug, e.g. "Men's Shoes" becomes "men-s-shoes"
+func Slugify(name string) string {
+	if slug := slugWords(name); slug != "" {
+		return slug
+	}
+	return "category"
+}
+
+// slugWords lowercases name and joins its runs of ASCII letters and digits
+// with hyphens, returning "" if it has none
+func slugWords(name string) string {
+	var slug strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(name) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			slug.WriteRune(r)
+			hyphen = false
+		} else if !hyphen && slug.Len() > 0 {
+			slug.WriteRune('-')
+			hyphen = true
+		}
+	}
+	return strings.TrimSuffix(slug.String(), "-")
+}
+
+// ValidSlug reports whether a slug chosen by an admin is well formed
+func ValidSlug(slug string) bool {
+	return slug != "" && Slugify(slug) == slug
+}
+
+// uniqueSlug returns base, or base with a numeric suffix, that no category
+// other than excludeID uses
+func uniqueSlug(tx *gorm.DB, base string, excludeID uint) (string, error) {
+	for i := 1; ; i++ {
+		slug := base
+		if i > 1 {
+			slug = fmt.Sprintf("%s-%d", base, i)
+		}
+		taken, err := slugTaken(tx, slug, excludeID)
+		if err != nil || !taken {
+			return slug, err
+		}
+	}
+}
+
+// slugTaken reports whether a category other than excludeID uses a slug
+func slugTaken(tx *gorm.DB, slug string, excludeID uint) (bool, error) {
+	var count int64
+	err := tx.Model(&models.Category{}).Where("slug = ? AND id <> ?", slug, excludeID).Count(&count).Error
+	return count > 0, err
+}
+
+// Create stores a new category under an optional parent. An empty slug is
+// generated from the name.
+func Create(tx *gorm.DB, category *models.Category) error {
+	var parent *models.Category
+	if category.ParentID != nil {
+		parent = &models.Category{}
+		if err := tx.First(parent, *category.ParentID).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := assignSlug(tx, category); err != nil {
+		return err
+	}
+
+	if err := tx.Create(category).Error; err != nil {
+		return err
+	}
+
+	category.Path = childPath(parent, category.ID)
+	return tx.Model(category).Update("path", category.Path).Error
+}
+
+// Update saves a category's name, slug, position and parent. Moving it to
+// another parent rewrites the paths of its whole subtree.
+func Update(tx *gorm.DB, category *models.Category, oldPath string) error {
+	var parent *models.Category
+	if category.ParentID != nil {
+		parent = &models.Category{}
+		if err := tx.First(parent, *category.ParentID).Error; err != nil {
+			return err
+		}
+		if strings.HasPrefix(parent.Path, oldPath) {
+			return ErrCategoryCycle
+		}
+	}
+
+	if err := assignSlug(tx, category); err != nil {
+		return err
+	}
+
+	category.Path = childPath(parent, category.ID)
+	if err := tx.Model(category).Select("name", "slug", "parent_id", "position", "path").Updates(category).Error; err != nil {
+		return err
+	}
+
+	if category.Path == oldPath {
+		return nil
+	}
+	return tx.Model(&models.Category{}).
+		Where("path LIKE ? AND id <> ?", oldPath+"%", category.ID).
+		Update("path", gorm.Expr("? || substr(path, ?)", category.Path, len(oldPath)+1)).Error
+}
+
+// assignSlug generates a slug from the name if none was given, or checks
+// that the given one is well formed and free
+func assignSlug(tx *gorm.DB, category *models.Category) error {
+	if category.Slug == "" {
+		slug, err := uniqueSlug(tx, Slugify(category.Name), category.ID)
+		category.Slug = slug
+		return err
+	}
+
+	if !ValidSlug(category.Slug) {
+		return ErrInvalidSlug
+	}
+	taken, err := slugTaken(tx, category.Slug, category.ID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrSlugTaken
+	}
+	return nil
+}
+
+// childPath returns the path of a category placed under parent, or at the root if parent is nil
+func childPath(parent *models.Category, id uint) string {
+	prefix := "/"
+	if parent != nil {
+		prefix = parent.Path
+	}
+	return prefix + strconv.FormatUint(uint64(id), 10) + "/"
+}
+
+// Reorder sets the position of each of a parent's children to its index in
+// ids, which must list every child exactly once
+func Reorder(tx *gorm.DB, parentID *uint, ids []uint) error {
+	query := tx.Model(&models.Category{})
+	if parentID == nil {
+		query = query.Where("parent_id IS NULL")
+	} else {
+		query = query.Where("parent_id = ?", *parentID)
+	}
+
+	var childIDs []uint
+	if err := query.Pluck("id", &childIDs).Error; err != nil {
+		return err
+	}
+
+	sorted := slices.Clone(ids)
+	slices.Sort(sorted)
+	slices.Sort(childIDs)
+	if !slices.Equal(sorted, childIDs) {
+		return ErrInvalidOrder
+	}
+
+	for position, id := range ids {
+		if err := tx.Model(&models.Category{}).Where("id = ?", id).Update("position", position).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SubtreeHasProducts reports whether any product is in the category at path or below it
+func SubtreeHasProducts(tx *gorm.DB, path string) (bool, error) {
+	var count int64
+	err := tx.Model(&models.Product{}).
+		Where("category_id IN (?)", tx.Model(&models.Category{}).Select("id").Where("path LIKE ?", path+"%")).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// Tree returns every category nested under its parent, each level sorted by position then name
+func Tree(db *gorm.DB) ([]*Node, error) {
+	var categories []models.Category
+	if err := db.Order("position, name, id").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[uint]*Node, len(categories))
+	for _, category := range categories {
+		nodes[category.ID] = &Node{Category: category, Children: []*Node{}}
+	}
+
+	roots := []*Node{}
+	for _, category := range categories {
+		node := nodes[category.ID]
+		if category.ParentID != nil {
+			if parent, ok := nodes[*category.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	return roots, nil
+}
+
+// Breadcrumbs returns the categories from the root down to the given one
+func Breadcrumbs(db *gorm.DB, categoryID uint) ([]models.Category, error) {
+	var category models.Category
+	if err := db.First(&category, categoryID).Error; err != nil {
+		return nil, err
+	}
+
+	var ids []uint
+	for _, part := range strings.Split(strings.Trim(category.Path, "/"), "/") {
+		id, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("category %d has a malformed path %q", category.ID, category.Path)
+		}
+		ids = append(ids, uint(id))
+	}
+
+	var ancestors []models.Category
+	if err := db.Where("id IN ?", ids).Find(&ancestors).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.Category, len(ancestors))
+	for _, ancestor := range ancestors {
+		byID[ancestor.ID] = ancestor
+	}
+
+	crumbs := make([]models.Category, 0, len(ids))
+	for _, id := range ids {
+		if ancestor, ok := byID[id]; ok {
+			crumbs = append(crumbs, ancestor)
+		}
+	}
+	return crumbs, nil
+}
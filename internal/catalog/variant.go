@@ -0,0 +1,383 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yourusername/ecommerce/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidOptions is returned for option lists with blank or repeated names or values
+	ErrInvalidOptions = errors.New("option names and values must be non-empty and unique")
+	// ErrTooManyVariants is returned when an option matrix has more than MaxVariants combinations
+	ErrTooManyVariants = errors.New("too many variants")
+	// ErrSKUTaken is returned when another variant already uses a SKU
+	ErrSKUTaken = errors.New("sku is already in use")
+	// ErrOutOfStock is returned when a variant has fewer units left than requested
+	ErrOutOfStock = errors.New("not enough stock")
+)
+
+// MaxVariants caps the number of variants one product's options may generate
+const MaxVariants = 500
+
+// Option is an option type with its values, in display order
+type Option struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+// VariantDefaults are the price and stock given to newly generated variants
+type VariantDefaults struct {
+	Price     *float64
+	Stock     int
+	SKUPrefix string
+}
+
+// MigrateVariants gives every product created before variants existed a
+// single variant holding its stock, and points old order items at it
+func MigrateVariants(db *gorm.DB) error {
+	var products []models.Product
+	err := db.Where("NOT EXISTS (SELECT 1 FROM variants WHERE variants.product_id = products.id)").
+		Find(&products).Error
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, product := range products {
+			sku, err := uniqueSKU(tx, defaultSKUPrefix(product), 0)
+			if err != nil {
+				return err
+			}
+			variant := models.Variant{ProductID: product.ID, SKU: sku, Stock: product.Stock}
+			if err := tx.Create(&variant).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Exec(`UPDATE order_items SET
+			variant_id = (SELECT MIN(id) FROM variants WHERE variants.product_id = order_items.product_id),
+			sku = (SELECT MIN(sku) FROM variants WHERE variants.product_id = order_items.product_id),
+			title = ''
+			WHERE variant_id IS NULL AND sku IS NULL
+			AND (SELECT COUNT(*) FROM variants WHERE variants.product_id = order_items.product_id) = 1`).Error
+	})
+}
+
+// CreateDefaultVariant adds the single variant of a new product without options
+func CreateDefaultVariant(tx *gorm.DB, product *models.Product) error {
+	sku, err := uniqueSKU(tx, defaultSKUPrefix(*product), 0)
+	if err != nil {
+		return err
+	}
+	variant := models.Variant{ProductID: product.ID, SKU: sku, Stock: product.Stock}
+	if err := tx.Create(&variant).Error; err != nil {
+		return err
+	}
+	product.Variants = []models.Variant{variant}
+	return nil
+}
+
+// GenerateVariants replaces a product's options and creates a variant for
+// every combination of their values. Variants whose combination still exists
+// keep their SKU, price, stock and images; the others are deleted, and orders
+// for them keep the SKU and title they were placed with. A product with no
+// options ends up with a single variant.
+func GenerateVariants(tx *gorm.DB, productID uint, options []Option, defaults VariantDefaults) error {
+	if err := validateOptions(options); err != nil {
+		return err
+	}
+	combinations := 1
+	for _, option := range options {
+		combinations *= len(option.Values)
+		if combinations > MaxVariants {
+			return ErrTooManyVariants
+		}
+	}
+
+	var product models.Product
+	err := tx.Preload("Options.Values").Preload("Variants.Options").First(&product, productID).Error
+	if err != nil {
+		return err
+	}
+
+	// Reuse option types and values that are already there, so variants
+	// built from them keep matching
+	types := make([]models.OptionType, len(options))
+	keepValues := map[uint]bool{}
+	keepTypes := map[uint]bool{}
+	for i, option := range options {
+		optionType := models.OptionType{ProductID: product.ID, Name: strings.TrimSpace(option.Name)}
+		var existing []models.OptionValue
+		if found := findOptionType(product.Options, option.Name); found != nil {
+			optionType = *found
+			existing = found.Values
+		}
+		optionType.Position = i
+		optionType.Values = nil
+		if err := tx.Omit("Values").Save(&optionType).Error; err != nil {
+			return err
+		}
+		keepTypes[optionType.ID] = true
+
+		for j, value := range option.Values {
+			optionValue := models.OptionValue{OptionTypeID: optionType.ID, Value: strings.TrimSpace(value)}
+			if found := findOptionValue(existing, value); found != nil {
+				optionValue = *found
+			}
+			optionValue.Position = j
+			if err := tx.Save(&optionValue).Error; err != nil {
+				return err
+			}
+			keepValues[optionValue.ID] = true
+			optionType.Values = append(optionType.Values, optionValue)
+		}
+		types[i] = optionType
+	}
+
+	// Keep variants whose combination is still offered and delete the rest
+	wanted := map[string][]models.OptionValue{}
+	for _, combination := range cartesian(types) {
+		wanted[combinationKey(combination)] = combination
+	}
+	for _, variant := range product.Variants {
+		key := combinationKey(variant.Options)
+		if _, ok := wanted[key]; ok {
+			delete(wanted, key)
+			continue
+		}
+		if err := tx.Select("Options").Delete(&variant).Error; err != nil {
+			return err
+		}
+	}
+
+	// Create the missing combinations in display order
+	prefix := defaults.SKUPrefix
+	if prefix == "" {
+		prefix = defaultSKUPrefix(product)
+	}
+	for _, combination := range cartesian(types) {
+		if _, ok := wanted[combinationKey(combination)]; !ok {
+			continue
+		}
+		parts := []string{prefix}
+		for _, value := range combination {
+			part := skuPart(value.Value)
+			if part == "" {
+				part = strconv.FormatUint(uint64(value.ID), 10)
+			}
+			parts = append(parts, part)
+		}
+		sku, err := uniqueSKU(tx, strings.Join(parts, "-"), 0)
+		if err != nil {
+			return err
+		}
+		variant := models.Variant{
+			ProductID: product.ID,
+			SKU:       sku,
+			Title:     variantTitle(combination),
+			Price:     defaults.Price,
+			Stock:     defaults.Stock,
+			Options:   combination,
+		}
+		if err := tx.Omit("Options.*").Create(&variant).Error; err != nil {
+			return err
+		}
+	}
+
+	// Drop the options and values no longer offered
+	for _, optionType := range product.Options {
+		for _, value := range optionType.Values {
+			if !keepValues[value.ID] {
+				if err := tx.Delete(&value).Error; err != nil {
+					return err
+				}
+			}
+		}
+		if !keepTypes[optionType.ID] {
+			if err := tx.Delete(&optionType).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	return SyncStock(tx, product.ID)
+}
+
+// UpdateVariant saves a variant's SKU, price override and stock
+func UpdateVariant(tx *gorm.DB, variant *models.Variant) error {
+	variant.SKU = strings.TrimSpace(variant.SKU)
+	taken, err := skuTaken(tx, variant.SKU, variant.ID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrSKUTaken
+	}
+
+	if err := tx.Model(variant).Select("sku", "price", "stock").Updates(variant).Error; err != nil {
+		return err
+	}
+	return SyncStock(tx, variant.ProductID)
+}
+
+// SetProductStock sets the stock of a product that has a single variant. The
+// stock of a product with options is the sum of its variants' and is left as is.
+func SetProductStock(tx *gorm.DB, productID uint, stock int) error {
+	var variants []models.Variant
+	if err := tx.Where("product_id = ?", productID).Find(&variants).Error; err != nil {
+		return err
+	}
+	if len(variants) == 1 {
+		if err := tx.Model(&variants[0]).Update("stock", stock).Error; err != nil {
+			return err
+		}
+	}
+	return SyncStock(tx, productID)
+}
+
+// ReserveStock takes units of a variant's stock for an order. The check and
+// the decrement are one conditional update, so concurrent orders can't sell
+// the same units twice; the product's total is adjusted by the same amount.
+func ReserveStock(tx *gorm.DB, variant *models.Variant, quantity int) error {
+	result := tx.Model(&models.Variant{}).
+		Where("id = ? AND stock >= ?", variant.ID, quantity).
+		Update("stock", gorm.Expr("stock - ?", quantity))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrOutOfStock
+	}
+
+	return tx.Model(&models.Product{}).Where("id = ?", variant.ProductID).
+		Update("stock", gorm.Expr("stock - ?", quantity)).Error
+}
+
+// SyncStock sets a product's stock to the total stock of its variants
+func SyncStock(tx *gorm.DB, productID uint) error {
+	return tx.Model(&models.Product{}).Where("id = ?", productID).
+		Update("stock", gorm.Expr("(SELECT COALESCE(SUM(stock), 0) FROM variants WHERE product_id = ?)", productID)).Error
+}
+
+// validateOptions checks that option names are unique and each option's values are
+func validateOptions(options []Option) error {
+	names := map[string]bool{}
+	for _, option := range options {
+		name := strings.ToLower(strings.TrimSpace(option.Name))
+		if name == "" || names[name] || len(option.Values) == 0 {
+			return ErrInvalidOptions
+		}
+		names[name] = true
+
+		values := map[string]bool{}
+		for _, value := range option.Values {
+			value = strings.ToLower(strings.TrimSpace(value))
+			if value == "" || values[value] {
+				return ErrInvalidOptions
+			}
+			values[value] = true
+		}
+	}
+	return nil
+}
+
+// findOptionType returns the option type with a name, ignoring case, or nil
+func findOptionType(types []models.OptionType, name string) *models.OptionType {
+	for i := range types {
+		if strings.EqualFold(types[i].Name, strings.TrimSpace(name)) {
+			return &types[i]
+		}
+	}
+	return nil
+}
+
+// findOptionValue returns the option value with a value, ignoring case, or nil
+func findOptionValue(values []models.OptionValue, value string) *models.OptionValue {
+	for i := range values {
+		if strings.EqualFold(values[i].Value, strings.TrimSpace(value)) {
+			return &values[i]
+		}
+	}
+	return nil
+}
+
+// cartesian returns every combination of one value from each option type, in
+// display order. With no option types there is one empty combination.
+func cartesian(types []models.OptionType) [][]models.OptionValue {
+	combinations := [][]models.OptionValue{{}}
+	for _, optionType := range types {
+		var next [][]models.OptionValue
+		for _, combination := range combinations {
+			for _, value := range optionType.Values {
+				next = append(next, append(combination[:len(combination):len(combination)], value))
+			}
+		}
+		combinations = next
+	}
+	return combinations
+}
+
+// combinationKey identifies a set of option values regardless of order
+func combinationKey(values []models.OptionValue) string {
+	ids := make([]string, len(values))
+	for i, value := range values {
+		ids[i] = strconv.FormatUint(uint64(value.ID), 10)
+	}
+	sort.Strings(ids)
+	return strings.Join(ids, ",")
+}
+
+// variantTitle joins a combination's values for display, e.g. "M / Red"
+func variantTitle(values []models.OptionValue) string {
+	parts := make([]string, len(values))
+	for i, value := range values {
+		parts[i] = value.Value
+	}
+	return strings.Join(parts, " / ")
+}
+
+// defaultSKUPrefix derives a SKU prefix from a product's name and ID, e.g. "TSHIRT-12"
+func defaultSKUPrefix(product models.Product) string {
+	name := strings.ReplaceAll(skuPart(product.Name), "-", "")
+	if len(name) > 12 {
+		name = name[:12]
+	}
+	if name == "" {
+		name = "SKU"
+	}
+	return fmt.Sprintf("%s-%d", name, product.ID)
+}
+
+// skuPart turns a name or option value into upper-case letters, digits and
+// hyphens for building SKUs. It returns "" if nothing usable is left.
+func skuPart(text string) string {
+	return strings.ToUpper(slugWords(text))
+}
+
+// uniqueSKU returns base, or base with a numeric suffix, that no variant
+// other than excludeID uses
+func uniqueSKU(tx *gorm.DB, base string, excludeID uint) (string, error) {
+	for i := 1; ; i++ {
+		sku := base
+		if i > 1 {
+			sku = fmt.Sprintf("%s-%d", base, i)
+		}
+		taken, err := skuTaken(tx, sku, excludeID)
+		if err != nil || !taken {
+			return sku, err
+		}
+	}
+}
+
+// skuTaken reports whether a variant other than excludeID uses a SKU
+func skuTaken(tx *gorm.DB, sku string, excludeID uint) (bool, error) {
+	var count int64
+	err := tx.Model(&models.Variant{}).Where("sku = ? AND id <> ?", sku, excludeID).Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,305 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/ecommerce/internal/models"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+)
+
+// takenSKUs returns a database that runs no SQL and answers SKU lookups as if
+// the given SKUs were used by variants other than the one being checked
+func takenSKUs(t *testing.T, skus ...string) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=invalid"}), &gorm.Config{
+		DryRun:                 true,
+		SkipDefaultTransaction: true,
+		DisableAutomaticPing:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Callback().Query().Replace("gorm:query", func(tx *gorm.DB) {
+		callbacks.BuildQuerySQL(tx)
+		count, ok := tx.Statement.Dest.(*int64)
+		if !ok || tx.Statement.Table != "variants" || len(tx.Statement.Vars) == 0 {
+			return
+		}
+		// Count reads the result from RowsAffected when it isn't one row
+		tx.RowsAffected = 1
+		*count = 0
+		if sku, ok := tx.Statement.Vars[0].(string); ok && slices.Contains(skus, sku) {
+			*count = 1
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func optionTypes(options ...Option) []models.OptionType {
+	var types []models.OptionType
+	id := uint(1)
+	for _, option := range options {
+		optionType := models.OptionType{Name: option.Name}
+		for _, value := range option.Values {
+			optionType.Values = append(optionType.Values, models.OptionValue{ID: id, Value: value})
+			id++
+		}
+		types = append(types, optionType)
+	}
+	return types
+}
+
+func TestCartesian(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		want    []string
+	}{
+		{"no options", nil, []string{""}},
+		{"one option", []Option{{"Size", []string{"S", "M", "L"}}}, []string{"S", "M", "L"}},
+		{
+			"two options in display order",
+			[]Option{{"Size", []string{"S", "M"}}, {"Color", []string{"Red", "Blue", "Green"}}},
+			[]string{"S / Red", "S / Blue", "S / Green", "M / Red", "M / Blue", "M / Green"},
+		},
+		{
+			"three options",
+			[]Option{{"Size", []string{"S", "M"}}, {"Color", []string{"Red", "Blue"}}, {"Fit", []string{"Slim", "Loose"}}},
+			[]string{
+				"S / Red / Slim", "S / Red / Loose", "S / Blue / Slim", "S / Blue / Loose",
+				"M / Red / Slim", "M / Red / Loose", "M / Blue / Slim", "M / Blue / Loose",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, combination := range cartesian(optionTypes(tt.options...)) {
+				got = append(got, variantTitle(combination))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("cartesian() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCartesianCombinationsDoNotShareValues(t *testing.T) {
+	// Building on a shared prefix must not let one combination overwrite another's values
+	types := optionTypes(
+		Option{"A", []string{"a1", "a2"}},
+		Option{"B", []string{"b1", "b2"}},
+		Option{"C", []string{"c1", "c2", "c3"}},
+	)
+	seen := map[string]bool{}
+	for _, combination := range cartesian(types) {
+		key := combinationKey(combination)
+		if seen[key] {
+			t.Fatalf("combination %s generated twice", variantTitle(combination))
+		}
+		seen[key] = true
+	}
+	if len(seen) != 12 {
+		t.Errorf("got %d distinct combinations, want 12", len(seen))
+	}
+}
+
+func TestCombinationKeyIgnoresOrder(t *testing.T) {
+	a := []models.OptionValue{{ID: 3}, {ID: 12}, {ID: 7}}
+	b := []models.OptionValue{{ID: 12}, {ID: 7}, {ID: 3}}
+	if combinationKey(a) != combinationKey(b) {
+		t.Errorf("combinationKey(%v) = %q, combinationKey(%v) = %q", a, combinationKey(a), b, combinationKey(b))
+	}
+	if combinationKey(a) == combinationKey(a[:2]) {
+		t.Error("combinations of different size have the same key")
+	}
+	if combinationKey(nil) != "" {
+		t.Errorf("combinationKey(nil) = %q, want empty", combinationKey(nil))
+	}
+}
+
+func TestValidateOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		wantErr bool
+	}{
+		{"no options", nil, false},
+		{"valid", []Option{{"Size", []string{"S", "M"}}, {"Color", []string{"Red"}}}, false},
+		{"blank name", []Option{{"  ", []string{"S"}}}, true},
+		{"no values", []Option{{"Size", nil}}, true},
+		{"blank value", []Option{{"Size", []string{"S", " "}}}, true},
+		{"repeated name ignoring case", []Option{{"Size", []string{"S"}}, {"size ", []string{"M"}}}, true},
+		{"repeated value ignoring case", []Option{{"Color", []string{"Red", " red"}}}, true},
+		{"same value in different options", []Option{{"Color", []string{"Red"}}, {"Trim", []string{"Red"}}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateOptions(tt.options)
+			if tt.wantErr && !errors.Is(err, ErrInvalidOptions) {
+				t.Errorf("validateOptions() = %v, want ErrInvalidOptions", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("validateOptions() = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestGenerateVariantsLimit(t *testing.T) {
+	values := func(n int) []string {
+		var result []string
+		for i := 0; i < n; i++ {
+			result = append(result, fmt.Sprint(i))
+		}
+		return result
+	}
+
+	// The limit is checked before the database is touched
+	over := []Option{{"Size", values(20)}, {"Color", values(26)}}
+	if err := GenerateVariants(nil, 1, over, VariantDefaults{}); !errors.Is(err, ErrTooManyVariants) {
+		t.Errorf("GenerateVariants(20x26) = %v, want ErrTooManyVariants", err)
+	}
+
+	// Overflowing products of many options are caught early
+	var many []Option
+	for i := 0; i < 64; i++ {
+		many = append(many, Option{fmt.Sprint("Option", i), values(2)})
+	}
+	if err := GenerateVariants(nil, 1, many, VariantDefaults{}); !errors.Is(err, ErrTooManyVariants) {
+		t.Errorf("GenerateVariants(2^64) = %v, want ErrTooManyVariants", err)
+	}
+
+	invalid := []Option{{"Size", []string{"S", "S"}}}
+	if err := GenerateVariants(nil, 1, invalid, VariantDefaults{}); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("GenerateVariants(invalid) = %v, want ErrInvalidOptions", err)
+	}
+
+	if MaxVariants != 500 {
+		t.Fatalf("MaxVariants = %d; update the cases below", MaxVariants)
+	}
+	atLimit := []Option{{"Size", values(20)}, {"Color", values(25)}}
+	combinations := 1
+	for _, option := range atLimit {
+		combinations *= len(option.Values)
+	}
+	if combinations != MaxVariants {
+		t.Fatalf("test options give %d combinations, want %d", combinations, MaxVariants)
+	}
+	if err := validateOptions(atLimit); err != nil {
+		t.Errorf("options at the limit are invalid: %v", err)
+	}
+}
+
+func TestSKUParts(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Red", "RED"},
+		{"Extra Large", "EXTRA-LARGE"},
+		{"  10.5 / Wide ", "10-5-WIDE"},
+		{"***", ""},
+	}
+	for _, tt := range tests {
+		if got := skuPart(tt.text); got != tt.want {
+			t.Errorf("skuPart(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+
+	for _, tt := range []struct {
+		product models.Product
+		want    string
+	}{
+		{models.Product{ID: 12, Name: "T-Shirt"}, "TSHIRT-12"},
+		{models.Product{ID: 3, Name: "Very Long Product Name Indeed"}, "VERYLONGPROD-3"},
+		{models.Product{ID: 7, Name: "!!!"}, "SKU-7"},
+	} {
+		if got := defaultSKUPrefix(tt.product); got != tt.want {
+			t.Errorf("defaultSKUPrefix(%q) = %q, want %q", tt.product.Name, got, tt.want)
+		}
+	}
+}
+
+func TestUniqueSKU(t *testing.T) {
+	tests := []struct {
+		name  string
+		taken []string
+		want  string
+	}{
+		{"free", nil, "TSHIRT-12-M"},
+		{"taken", []string{"TSHIRT-12-M"}, "TSHIRT-12-M-2"},
+		{"several taken", []string{"TSHIRT-12-M", "TSHIRT-12-M-2", "TSHIRT-12-M-3"}, "TSHIRT-12-M-4"},
+		{"only a suffixed one taken", []string{"TSHIRT-12-M-2"}, "TSHIRT-12-M"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := uniqueSKU(takenSKUs(t, tt.taken...), "TSHIRT-12-M", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("uniqueSKU() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateVariantRejectsTakenSKU(t *testing.T) {
+	db := takenSKUs(t, "MUG-4")
+
+	variant := models.Variant{ID: 9, ProductID: 4, SKU: "  MUG-4 "}
+	if err := UpdateVariant(db, &variant); !errors.Is(err, ErrSKUTaken) {
+		t.Errorf("UpdateVariant() = %v, want ErrSKUTaken", err)
+	}
+	if variant.SKU != "MUG-4" {
+		t.Errorf("SKU = %q, want it trimmed", variant.SKU)
+	}
+
+	variant.SKU = "MUG-4-BLUE"
+	if err := UpdateVariant(db, &variant); err != nil {
+		t.Errorf("UpdateVariant() with a free SKU = %v", err)
+	}
+}
+
+func TestReserveStockIsConditional(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=invalid"}), &gorm.Config{
+		DryRun:                 true,
+		SkipDefaultTransaction: true,
+		DisableAutomaticPing:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var statements []string
+	db.Callback().Update().After("gorm:update").Register("test:record", func(tx *gorm.DB) {
+		statements = append(statements, tx.Dialector.Explain(tx.Statement.SQL.String(), tx.Statement.Vars...))
+	})
+
+	// A dry run affects no rows, which is what a sold-out variant looks like
+	err = ReserveStock(db, &models.Variant{ID: 3, ProductID: 8}, 2)
+	if !errors.Is(err, ErrOutOfStock) {
+		t.Fatalf("ReserveStock() = %v, want ErrOutOfStock", err)
+	}
+	if len(statements) != 1 {
+		t.Fatalf("ran %d statements, want only the variant update: %q", len(statements), statements)
+	}
+	if !strings.HasPrefix(statements[0], `UPDATE "variants" SET "stock"=stock - 2`) ||
+		!strings.HasSuffix(statements[0], `WHERE id = 3 AND stock >= 2`) {
+		t.Errorf("statement = %q, want a decrement conditional on the stock left", statements[0])
+	}
+}
@@ -0,0 +1,146 @@
+package geoip
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Location is where an IP address approximately is
+type Location struct {
+	Country string `json:"country"`
+	Region  string `json:"region,omitempty"`
+	City    string `json:"city,omitempty"`
+}
+
+// String formats the location as "City, Region, Country", leaving out unknown parts
+func (l Location) String() string {
+	var parts []string
+	for _, part := range []string{l.City, l.Region, l.Country} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ipRange is one network from the database as its first and last address
+type ipRange struct {
+	first    netip.Addr
+	last     netip.Addr
+	location Location
+}
+
+// Database maps IP networks to locations
+type Database struct {
+	ranges []ipRange
+}
+
+// LoadCSV reads a database from a CSV file with a header row and the columns
+// network,country,region,city, where network is a CIDR block such as
+// 81.2.69.0/24. Networks must not overlap.
+func LoadCSV(path string) (*Database, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+
+	// Skip the header
+	if _, err := reader.Read(); err != nil {
+		return nil, err
+	}
+
+	db := &Database{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < 2 {
+			line, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("%s:%d: expected network and country", path, line)
+		}
+
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(record[0]))
+		if err != nil {
+			line, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		prefix = prefix.Masked()
+
+		location := Location{Country: strings.TrimSpace(record[1])}
+		if len(record) > 2 {
+			location.Region = strings.TrimSpace(record[2])
+		}
+		if len(record) > 3 {
+			location.City = strings.TrimSpace(record[3])
+		}
+
+		db.ranges = append(db.ranges, ipRange{
+			first:    prefix.Addr(),
+			last:     lastAddr(prefix),
+			location: location,
+		})
+	}
+
+	sort.Slice(db.ranges, func(i, j int) bool {
+		return db.ranges[i].first.Less(db.ranges[j].first)
+	})
+
+	return db, nil
+}
+
+// Lookup returns the location of an IP address; ok is false if it isn't in
+// the database or isn't a valid address
+func (db *Database) Lookup(ip string) (Location, bool) {
+	if db == nil {
+		return Location{}, false
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return Location{}, false
+	}
+	addr = addr.Unmap()
+
+	// Find the last range starting at or before the address
+	i := sort.Search(len(db.ranges), func(i int) bool {
+		return addr.Less(db.ranges[i].first)
+	}) - 1
+	if i < 0 {
+		return Location{}, false
+	}
+
+	r := db.ranges[i]
+	if r.first.BitLen() != addr.BitLen() || r.last.Less(addr) {
+		return Location{}, false
+	}
+	return r.location, true
+}
+
+// lastAddr returns the highest address in a prefix
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	bytes := prefix.Addr().AsSlice()
+	bits := prefix.Bits()
+	for i := range bytes {
+		for b := 7; b >= 0; b-- {
+			if i*8+(7-b) >= bits {
+				bytes[i] |= 1 << b
+			}
+		}
+	}
+	addr, _ := netip.AddrFromSlice(bytes)
+	return addr
+}
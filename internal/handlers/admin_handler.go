@@ -0,0 +1,325 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/ecommerce/configs"
+	"github.com/yourusername/ecommerce/internal/audit"
+	"github.com/yourusername/ecommerce/internal/auth"
+	"github.com/yourusername/ecommerce/internal/database"
+	"github.com/yourusername/ecommerce/internal/models"
+	"github.com/yourusername/ecommerce/internal/rbac"
+	"github.com/yourusername/ecommerce/internal/throttle"
+)
+
+// AdminHandler handles admin account-management requests
+type AdminHandler struct {
+	config     *configs.Config
+	loginGuard *throttle.LoginGuard
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(config *configs.Config, loginGuard *throttle.LoginGuard) *AdminHandler {
+	return &AdminHandler{
+		config:     config,
+		loginGuard: loginGuard,
+	}
+}
+
+// UnlockUser lifts a login lockout on a user's account
+func (h *AdminHandler) UnlockUser(c *gin.Context) {
+	id := c.Param("id")
+
+	var user models.User
+	if err := database.GetDB().First(&user, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := h.loginGuard.Unlock(user.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock account"})
+		return
+	}
+
+	audit.Record(c, audit.Event{
+		Action:     "user.unlocked",
+		TargetType: "user",
+		TargetID:   audit.ID(user.ID),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account unlocked"})
+}
+
+// GetUsers returns users matching an optional search term, role and disabled filter
+func (h *AdminHandler) GetUsers(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	query := database.GetDB().Model(&models.User{})
+	if search := strings.TrimSpace(c.Query("q")); search != "" {
+		pattern := "%" + search + "%"
+		query = query.Where("email ILIKE ? OR first_name ILIKE ? OR last_name ILIKE ?", pattern, pattern, pattern)
+	}
+	if role := c.Query("role"); role != "" {
+		query = query.Where("role = ?", role)
+	}
+	if disabled := c.Query("disabled"); disabled != "" {
+		query = query.Where("disabled = ?", disabled == "true")
+	}
+
+	var count int64
+	query.Count(&count)
+
+	var users []models.User
+	query.Order("id").Offset((page - 1) * limit).Limit(limit).Find(&users)
+
+	c.JSON(http.StatusOK, gin.H{
+		"users": users,
+		"total": count,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+// GetUser returns a single user
+func (h *AdminHandler) GetUser(c *gin.Context) {
+	user, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user": user})
+}
+
+// UpdateUserRole assigns a user a different role. The user's sessions are
+// ended so the new permissions apply straight away.
+func (h *AdminHandler) UpdateUserRole(c *gin.Context) {
+	user, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+
+	var roleData struct {
+		Role string `json:"role" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&roleData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	exists, err := rbac.RoleExists(roleData.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown role: " + roleData.Role})
+		return
+	}
+
+	if user.Role == roleData.Role {
+		c.JSON(http.StatusOK, gin.H{"user": user})
+		return
+	}
+
+	if !checkNotLastAdmin(c, user) {
+		return
+	}
+
+	if err := database.GetDB().Model(&models.User{}).Where("id = ?", user.ID).Update("role", roleData.Role).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+
+	audit.Record(c, audit.Event{
+		Action:     "user.role.changed",
+		TargetType: "user",
+		TargetID:   audit.ID(user.ID),
+		Diff:       map[string]audit.Change{"role": {From: user.Role, To: roleData.Role}},
+	})
+	user.Role = roleData.Role
+
+	if err := auth.RevokeUserTokens(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Role updated but failed to end sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user": user})
+}
+
+// DisableUser blocks an account from logging in and ends its sessions
+func (h *AdminHandler) DisableUser(c *gin.Context) {
+	user, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+
+	if userID, _ := c.Get("userID"); userID == user.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot disable your own account"})
+		return
+	}
+
+	if !checkNotLastAdmin(c, user) {
+		return
+	}
+
+	if err := database.GetDB().Model(&models.User{}).Where("id = ?", user.ID).Update("disabled", true).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable account"})
+		return
+	}
+
+	audit.Record(c, audit.Event{
+		Action:     "user.disabled",
+		TargetType: "user",
+		TargetID:   audit.ID(user.ID),
+	})
+
+	if err := auth.RevokeUserTokens(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Account disabled but failed to end sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account disabled"})
+}
+
+// EnableUser lets a disabled account log in again
+func (h *AdminHandler) EnableUser(c *gin.Context) {
+	user, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+
+	if err := database.GetDB().Model(&models.User{}).Where("id = ?", user.ID).Update("disabled", false).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable account"})
+		return
+	}
+
+	audit.Record(c, audit.Event{
+		Action:     "user.enabled",
+		TargetType: "user",
+		TargetID:   audit.ID(user.ID),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account enabled"})
+}
+
+// LogoutUser ends every session of a user
+func (h *AdminHandler) LogoutUser(c *gin.Context) {
+	user, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+
+	if err := auth.RevokeUserTokens(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to end sessions"})
+		return
+	}
+
+	audit.Record(c, audit.Event{
+		Action:     "user.sessions.revoked",
+		TargetType: "user",
+		TargetID:   audit.ID(user.ID),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "User logged out of all sessions"})
+}
+
+// Impersonate issues a short-lived token to act as a customer. Accounts whose
+// role grants any permission can't be impersonated, so this can't be used to
+// gain access an admin doesn't already have.
+func (h *AdminHandler) Impersonate(c *gin.Context) {
+	user, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+
+	adminID, _ := c.Get("userID")
+	if adminID == user.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot impersonate yourself"})
+		return
+	}
+
+	if user.Disabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Account is disabled"})
+		return
+	}
+
+	permissions, err := rbac.PermissionsForRole(user.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check user's role"})
+		return
+	}
+	if len(permissions) > 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Staff accounts cannot be impersonated"})
+		return
+	}
+
+	var admin models.User
+	if err := database.GetDB().First(&admin, adminID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	token, expiresAt, err := auth.GenerateImpersonationToken(user, &admin, h.config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	audit.Record(c, audit.Event{
+		Action:     "user.impersonation.started",
+		TargetType: "user",
+		TargetID:   audit.ID(user.ID),
+		Diff:       gin.H{"expires_at": expiresAt},
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":      token,
+		"expires_in": int64(h.config.ImpersonationTTL.Seconds()),
+		"user": gin.H{
+			"id":    user.ID,
+			"email": user.Email,
+		},
+	})
+}
+
+// findUser loads the user named by the :id parameter, writing a 404 response if there is none
+func (h *AdminHandler) findUser(c *gin.Context) (*models.User, bool) {
+	var user models.User
+	if err := database.GetDB().First(&user, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil, false
+	}
+	return &user, true
+}
+
+// checkNotLastAdmin writes a 409 response and returns false if the user is
+// the only enabled admin, so the system can't be left without one
+func checkNotLastAdmin(c *gin.Context, user *models.User) bool {
+	if user.Role != rbac.RoleAdmin || user.Disabled {
+		return true
+	}
+
+	var otherAdmins int64
+	if err := database.GetDB().Model(&models.User{}).
+		Where("role = ? AND disabled = ? AND id <> ?", rbac.RoleAdmin, false, user.ID).
+		Count(&otherAdmins).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check admin accounts"})
+		return false
+	}
+	if otherAdmins == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Cannot remove the last admin"})
+		return false
+	}
+	return true
+}
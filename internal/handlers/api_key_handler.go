@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/ecommerce/internal/audit"
+	"github.com/yourusername/ecommerce/internal/auth"
+	"github.com/yourusername/ecommerce/internal/database"
+	"github.com/yourusername/ecommerce/internal/models"
+	"github.com/yourusername/ecommerce/internal/rbac"
+)
+
+// APIKeyHandler handles API key management requests
+type APIKeyHandler struct{}
+
+// NewAPIKeyHandler creates a new API key handler
+func NewAPIKeyHandler() *APIKeyHandler {
+	return &APIKeyHandler{}
+}
+
+// GetAPIKeys returns all API keys, optionally filtered by owning user
+func (h *APIKeyHandler) GetAPIKeys(c *gin.Context) {
+	query := database.GetDB().Preload("Scopes")
+	if userID := c.Query("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var keys []models.APIKey
+	query.Order("created_at DESC").Find(&keys)
+
+	c.JSON(http.StatusOK, gin.H{"api_keys": keys})
+}
+
+// CreateAPIKey creates an API key acting as the given user. The raw key is
+// only returned in this response. Callers can only grant scopes they hold,
+// and need users:manage to create keys for staff accounts.
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var keyData struct {
+		Name      string     `json:"name" binding:"required"`
+		UserID    uint       `json:"user_id" binding:"required"`
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+
+	if err := c.ShouldBindJSON(&keyData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if keyData.ExpiresAt != nil && keyData.ExpiresAt.Before(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Expiry must be in the future"})
+		return
+	}
+
+	var user models.User
+	if err := database.GetDB().First(&user, keyData.UserID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// Acting as a staff account needs more than the right to manage keys
+	rolePermissions, err := rbac.PermissionsForRole(user.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+	granted := c.GetStringSlice("permissions")
+	if len(rolePermissions) > 0 && !slices.Contains(granted, rbac.UsersManage) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission required to create keys for staff accounts: " + rbac.UsersManage})
+		return
+	}
+
+	// A key can't be given more than its owner's role grants, nor more than
+	// the caller holds
+	for _, scope := range keyData.Scopes {
+		if !rbac.IsKnownPermission(scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown scope: " + scope})
+			return
+		}
+		if !slices.Contains(rolePermissions, scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "User's role does not grant scope: " + scope})
+			return
+		}
+		if !slices.Contains(granted, scope) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You cannot grant a scope you do not hold: " + scope})
+			return
+		}
+	}
+
+	scopes := []models.Permission{}
+	if len(keyData.Scopes) > 0 {
+		if err := database.GetDB().Where("name IN ?", keyData.Scopes).Find(&scopes).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+			return
+		}
+	}
+
+	rawKey, prefix, keyHash, err := auth.NewAPIKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+
+	creatorID, _ := c.Get("userID")
+	key := models.APIKey{
+		Name:        keyData.Name,
+		UserID:      user.ID,
+		Prefix:      prefix,
+		KeyHash:     keyHash,
+		Scopes:      scopes,
+		CreatedByID: creatorID.(uint),
+		ExpiresAt:   keyData.ExpiresAt,
+	}
+	if err := database.GetDB().Create(&key).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+
+	audit.Record(c, audit.Event{
+		Action:     "api_key.created",
+		TargetType: "api_key",
+		TargetID:   audit.ID(key.ID),
+		Diff:       gin.H{"name": key.Name, "user_id": key.UserID, "prefix": key.Prefix, "scopes": key.ScopeNames(), "created_by_id": key.CreatedByID, "expires_at": key.ExpiresAt},
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"api_key": key,
+		"key":     rawKey,
+	})
+}
+
+// RevokeAPIKey revokes an API key so it is no longer accepted
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	id := c.Param("id")
+
+	var key models.APIKey
+	if err := database.GetDB().First(&key, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+
+	if key.RevokedAt == nil {
+		if err := database.GetDB().Model(&key).Update("revoked_at", time.Now()).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+			return
+		}
+
+		audit.Record(c, audit.Event{
+			Action:     "api_key.revoked",
+			TargetType: "api_key",
+			TargetID:   audit.ID(key.ID),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked successfully"})
+}
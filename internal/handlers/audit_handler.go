@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/ecommerce/internal/audit"
+	"github.com/yourusername/ecommerce/internal/database"
+	"github.com/yourusername/ecommerce/internal/models"
+)
+
+// AuditHandler handles audit log queries
+type AuditHandler struct{}
+
+// NewAuditHandler creates a new audit handler
+func NewAuditHandler() *AuditHandler {
+	return &AuditHandler{}
+}
+
+// GetEvents returns audit events, newest first, filtered by actor, action,
+// target and time range
+func (h *AuditHandler) GetEvents(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	query := database.GetDB().Model(&models.AuditEvent{})
+	if actorID := c.Query("actor_id"); actorID != "" {
+		query = query.Where("actor_id = ?", actorID)
+	}
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if targetType := c.Query("target_type"); targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+	if targetID := c.Query("target_id"); targetID != "" {
+		query = query.Where("target_id = ?", targetID)
+	}
+	if requestID := c.Query("request_id"); requestID != "" {
+		query = query.Where("request_id = ?", requestID)
+	}
+	for param, condition := range map[string]string{"from": "created_at >= ?", "to": "created_at < ?"} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param + " time, expected RFC 3339"})
+			return
+		}
+		query = query.Where(condition, t)
+	}
+
+	var count int64
+	query.Count(&count)
+
+	var events []models.AuditEvent
+	query.Order("id DESC").Offset((page - 1) * limit).Limit(limit).Find(&events)
+
+	c.JSON(http.StatusOK, gin.H{
+		"events": events,
+		"total":  count,
+		"page":   page,
+		"limit":  limit,
+	})
+}
+
+// VerifyChain checks that no audit event has been altered or removed
+func (h *AuditHandler) VerifyChain(c *gin.Context) {
+	brokenAt, err := audit.Verify()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify audit log"})
+		return
+	}
+
+	if brokenAt != 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"intact":    false,
+			"broken_at": brokenAt,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"intact": true})
+}
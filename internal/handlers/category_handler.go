@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/ecommerce/internal/audit"
+	"github.com/yourusername/ecommerce/internal/catalog"
+	"github.com/yourusername/ecommerce/internal/database"
+	"github.com/yourusername/ecommerce/internal/models"
+	"gorm.io/gorm"
+)
+
+// CategoryHandler handles category-related requests
+type CategoryHandler struct{}
+
+// NewCategoryHandler creates a new category handler
+func NewCategoryHandler() *CategoryHandler {
+	return &CategoryHandler{}
+}
+
+// categoryData is the body accepted when creating or updating a category
+type categoryData struct {
+	Name     string `json:"name" binding:"required"`
+	Slug     string `json:"slug"`
+	ParentID *uint  `json:"parent_id"`
+	Position int    `json:"position"`
+}
+
+// GetTree returns every category nested under its parent
+func (h *CategoryHandler) GetTree(c *gin.Context) {
+	tree, err := catalog.Tree(database.GetDB())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load categories"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"categories": tree})
+}
+
+// GetCategory returns a category with its breadcrumb trail
+func (h *CategoryHandler) GetCategory(c *gin.Context) {
+	category, ok := h.findCategory(c)
+	if !ok {
+		return
+	}
+
+	crumbs, err := catalog.Breadcrumbs(database.GetDB(), category.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load category"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"category": category, "breadcrumbs": crumbs})
+}
+
+// GetProductBreadcrumbs returns the categories from the root down to a product's category
+func (h *CategoryHandler) GetProductBreadcrumbs(c *gin.Context) {
+	var product models.Product
+	if err := database.GetDB().Select("id", "category_id").First(&product, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	crumbs := []models.Category{}
+	if product.CategoryID != 0 {
+		var err error
+		crumbs, err = catalog.Breadcrumbs(database.GetDB(), product.CategoryID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load categories"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"breadcrumbs": crumbs})
+}
+
+// CreateCategory creates a category, at the root or under a parent
+func (h *CategoryHandler) CreateCategory(c *gin.Context) {
+	var data categoryData
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category := models.Category{
+		Name:     data.Name,
+		Slug:     data.Slug,
+		ParentID: data.ParentID,
+		Position: data.Position,
+	}
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		return catalog.Create(tx, &category)
+	})
+	if !h.checkSaveError(c, err, "Failed to create category") {
+		return
+	}
+
+	audit.Record(c, audit.Event{
+		Action:     "category.created",
+		TargetType: "category",
+		TargetID:   audit.ID(category.ID),
+		Diff:       audit.Changes(nil, category),
+	})
+
+	c.JSON(http.StatusCreated, gin.H{"category": category})
+}
+
+// UpdateCategory renames, re-slugs or moves a category. Moving it takes its
+// subcategories along.
+func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
+	category, ok := h.findCategory(c)
+	if !ok {
+		return
+	}
+	before := category
+
+	var data categoryData
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category.Name = data.Name
+	category.Slug = data.Slug
+	category.ParentID = data.ParentID
+	category.Position = data.Position
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		return catalog.Update(tx, &category, before.Path)
+	})
+	if !h.checkSaveError(c, err, "Failed to update category") {
+		return
+	}
+
+	audit.Record(c, audit.Event{
+		Action:     "category.updated",
+		TargetType: "category",
+		TargetID:   audit.ID(category.ID),
+		Diff:       audit.Changes(before, category),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"category": category})
+}
+
+// ReorderCategories sets the order of a parent's subcategories, or of the
+// top-level categories if parent_id is null
+func (h *CategoryHandler) ReorderCategories(c *gin.Context) {
+	var orderData struct {
+		ParentID *uint  `json:"parent_id"`
+		Order    []uint `json:"order" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&orderData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		return catalog.Reorder(tx, orderData.ParentID, orderData.Order)
+	})
+	if errors.Is(err, catalog.ErrInvalidOrder) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Order must list every subcategory exactly once"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder categories"})
+		return
+	}
+
+	audit.Record(c, audit.Event{
+		Action:     "category.reordered",
+		TargetType: "category",
+		TargetID:   audit.ID(derefID(orderData.ParentID)),
+		Diff:       gin.H{"order": orderData.Order},
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Categories reordered successfully"})
+}
+
+// DeleteCategory deletes a category that has no subcategories and no products
+func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
+	category, ok := h.findCategory(c)
+	if !ok {
+		return
+	}
+
+	var children int64
+	database.GetDB().Model(&models.Category{}).Where("parent_id = ?", category.ID).Count(&children)
+	if children > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Category still has subcategories"})
+		return
+	}
+
+	hasProducts, err := catalog.SubtreeHasProducts(database.GetDB(), category.Path)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete category"})
+		return
+	}
+	if hasProducts {
+		c.JSON(http.StatusConflict, gin.H{"error": "Category still has products"})
+		return
+	}
+
+	if err := database.GetDB().Delete(&category).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete category"})
+		return
+	}
+
+	audit.Record(c, audit.Event{
+		Action:     "category.deleted",
+		TargetType: "category",
+		TargetID:   audit.ID(category.ID),
+		Diff:       audit.Changes(category, nil),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Category deleted successfully"})
+}
+
+// findCategory loads the category named by the id parameter, writing a 404 response if it doesn't exist
+func (h *CategoryHandler) findCategory(c *gin.Context) (models.Category, bool) {
+	var category models.Category
+	if err := database.GetDB().First(&category, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return category, false
+	}
+	return category, true
+}
+
+// checkSaveError writes the response for an error from creating or updating a category
+func (h *CategoryHandler) checkSaveError(c *gin.Context, err error, message string) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parent category not found"})
+	case errors.Is(err, catalog.ErrCategoryCycle):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A category cannot be moved under itself or its subcategories"})
+	case errors.Is(err, catalog.ErrInvalidSlug):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Slug may only contain lowercase letters, digits and hyphens"})
+	case errors.Is(err, catalog.ErrSlugTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": "Slug is already in use"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+	return false
+}
+
+// derefID returns the ID a pointer holds, or 0 for nil
+func derefID(id *uint) uint {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
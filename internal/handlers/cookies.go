@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/ecommerce/configs"
+	"github.com/yourusername/ecommerce/internal/auth"
+	"github.com/yourusername/ecommerce/internal/middleware"
+)
+
+// Paths the session cookies are sent to. The refresh token only ever goes
+// to the auth routes.
+const (
+	accessCookiePath  = "/api"
+	refreshCookiePath = "/api/auth"
+)
+
+// setSessionCookies stores a token pair in HttpOnly cookies along with a
+// fresh CSRF token readable by the page, and returns the CSRF token
+func setSessionCookies(c *gin.Context, tokens *auth.TokenPair, config *configs.Config) (string, error) {
+	csrfToken, err := auth.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	setCookie(c, middleware.AccessTokenCookie, tokens.AccessToken, accessCookiePath, config.AccessTokenTTL, true, config)
+	setCookie(c, middleware.RefreshTokenCookie, tokens.RefreshToken, refreshCookiePath, config.RefreshTokenTTL, true, config)
+	setCookie(c, middleware.CSRFTokenCookie, csrfToken, "/", config.RefreshTokenTTL, false, config)
+
+	return csrfToken, nil
+}
+
+// clearSessionCookies removes the cookies set by setSessionCookies
+func clearSessionCookies(c *gin.Context, config *configs.Config) {
+	setCookie(c, middleware.AccessTokenCookie, "", accessCookiePath, -1, true, config)
+	setCookie(c, middleware.RefreshTokenCookie, "", refreshCookiePath, -1, true, config)
+	setCookie(c, middleware.CSRFTokenCookie, "", "/", -1, false, config)
+}
+
+// setCookie writes a cookie with the configured domain, Secure flag and
+// SameSite mode; a negative maxAge deletes it
+func setCookie(c *gin.Context, name, value, path string, maxAge time.Duration, httpOnly bool, config *configs.Config) {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   config.CookieDomain,
+		MaxAge:   int(maxAge.Seconds()),
+		Secure:   config.CookieSecure,
+		HttpOnly: httpOnly,
+		SameSite: sameSiteMode(config.CookieSameSite),
+	}
+	if maxAge < 0 {
+		cookie.MaxAge = -1
+	}
+	http.SetCookie(c.Writer, cookie)
+}
+
+// sameSiteMode parses the COOKIE_SAMESITE setting, defaulting to Lax
+func sameSiteMode(mode string) http.SameSite {
+	switch strings.ToLower(mode) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
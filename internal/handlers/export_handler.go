@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/ecommerce/configs"
+	"github.com/yourusername/ecommerce/internal/account"
+	"github.com/yourusername/ecommerce/internal/audit"
+	"github.com/yourusername/ecommerce/internal/database"
+	"github.com/yourusername/ecommerce/internal/models"
+)
+
+// ExportHandler handles personal data export requests
+type ExportHandler struct {
+	config   *configs.Config
+	exporter *account.Exporter
+}
+
+// NewExportHandler creates a new export handler
+func NewExportHandler(config *configs.Config) *ExportHandler {
+	return &ExportHandler{
+		config:   config,
+		exporter: account.NewExporter(config),
+	}
+}
+
+// RequestExport starts an export of the current user's data. A download
+// link is emailed once the archive is ready.
+func (h *ExportHandler) RequestExport(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	wait, err := h.exporter.Wait(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check export requests"})
+		return
+	}
+	if wait > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many exports requested, please try again later"})
+		return
+	}
+
+	h.startExport(c, userID.(uint), userID.(uint))
+}
+
+// GetExports returns the current user's exports
+func (h *ExportHandler) GetExports(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var exports []models.DataExport
+	database.GetDB().Where("user_id = ?", userID).Order("created_at DESC").Find(&exports)
+
+	c.JSON(http.StatusOK, gin.H{"exports": exports})
+}
+
+// RequestUserExport starts an export of any user's data for a subject access
+// request. The download link is emailed to the requesting admin.
+func (h *ExportHandler) RequestUserExport(c *gin.Context) {
+	adminID, _ := c.Get("userID")
+
+	var user models.User
+	if err := database.GetDB().First(&user, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	h.startExport(c, user.ID, adminID.(uint))
+}
+
+// startExport requests an export and writes the response. If one is already
+// being built for the user it is returned and nothing new is started.
+func (h *ExportHandler) startExport(c *gin.Context, userID, requestedByID uint) {
+	export, started, err := h.exporter.RequestExport(userID, requestedByID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start export"})
+		return
+	}
+
+	if !started {
+		c.JSON(http.StatusAccepted, gin.H{
+			"message": "An export is already being prepared. A download link will be emailed when it is ready.",
+			"export":  export,
+		})
+		return
+	}
+
+	audit.Record(c, audit.Event{
+		Action:     "user.export.requested",
+		TargetType: "user",
+		TargetID:   audit.ID(export.UserID),
+	})
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Export started. A download link will be emailed when it is ready.",
+		"export":  export,
+	})
+}
+
+// Download serves an export archive through its time-limited link
+func (h *ExportHandler) Download(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Download token is required"})
+		return
+	}
+
+	export, err := account.FindDownload(token)
+	if err != nil {
+		if errors.Is(err, account.ErrInvalidExportToken) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Invalid or expired download link"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find export"})
+		return
+	}
+
+	audit.Record(c, audit.Event{
+		Action:     "user.export.downloaded",
+		TargetType: "user",
+		TargetID:   audit.ID(export.UserID),
+	})
+
+	c.FileAttachment(export.FilePath, fmt.Sprintf("account-export-%d.zip", export.UserID))
+}
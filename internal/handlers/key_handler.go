@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/ecommerce/internal/auth"
+)
+
+// KeyHandler publishes the public keys used to verify tokens
+type KeyHandler struct{}
+
+// NewKeyHandler creates a new key handler
+func NewKeyHandler() *KeyHandler {
+	return &KeyHandler{}
+}
+
+// GetJWKS returns the JSON Web Key Set of all keys tokens may be signed with
+func (h *KeyHandler) GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, auth.PublicJWKS())
+}
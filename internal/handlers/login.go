@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/ecommerce/configs"
+	"github.com/yourusername/ecommerce/internal/audit"
+	"github.com/yourusername/ecommerce/internal/auth"
+	"github.com/yourusername/ecommerce/internal/models"
+)
+
+// completeLogin finishes a login once the user's primary credentials have been
+// checked. Users with two-factor enabled get an mfa_pending token instead of
+// a session; it can only be exchanged at /api/auth/mfa.
+func completeLogin(c *gin.Context, user *models.User, config *configs.Config) {
+	if !checkNotDisabled(c, user) {
+		return
+	}
+
+	audit.Record(c, audit.Event{
+		ActorID:    &user.ID,
+		Action:     "auth.login.succeeded",
+		TargetType: "user",
+		TargetID:   audit.ID(user.ID),
+	})
+
+	if user.MFAEnabled() {
+		mfaToken, err := auth.GeneratePurposeToken(auth.PurposeMFAPending, user.ID, user.Email, config.MFAPendingTTL, config)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":      "Two-factor authentication required",
+			"mfa_required": true,
+			"mfa_token":    mfaToken,
+			"expires_in":   int64(config.MFAPendingTTL.Seconds()),
+		})
+		return
+	}
+
+	respondWithTokens(c, http.StatusOK, "Login successful", user, config)
+}
+
+// respondWithTokens starts a new session for the user and writes the tokens
+func respondWithTokens(c *gin.Context, status int, message string, user *models.User, config *configs.Config) {
+	if !checkNotDisabled(c, user) {
+		return
+	}
+
+	tokens, err := auth.IssueTokenPair(user, clientInfo(c), config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	audit.Record(c, audit.Event{
+		ActorID:    &user.ID,
+		Action:     "auth.token.issued",
+		TargetType: "user",
+		TargetID:   audit.ID(user.ID),
+	})
+
+	response := gin.H{
+		"message":    message,
+		"expires_in": tokens.ExpiresIn,
+		"user": gin.H{
+			"id":         user.ID,
+			"email":      user.Email,
+			"first_name": user.FirstName,
+			"last_name":  user.LastName,
+			"role":       user.Role,
+		},
+	}
+	if !writeTokens(c, response, tokens, config) {
+		return
+	}
+
+	c.JSON(status, response)
+}
+
+// writeTokens adds a token pair to a response body or, in cookie mode, sets
+// it in cookies so page scripts never see it and adds the CSRF token instead
+func writeTokens(c *gin.Context, response gin.H, tokens *auth.TokenPair, config *configs.Config) bool {
+	if !config.CookieAuth {
+		response["token"] = tokens.AccessToken
+		response["refresh_token"] = tokens.RefreshToken
+		return true
+	}
+
+	csrfToken, err := setSessionCookies(c, tokens, config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return false
+	}
+	response["csrf_token"] = csrfToken
+	return true
+}
+
+// clientInfo describes the device making the request, for recording on its session
+func clientInfo(c *gin.Context) auth.ClientInfo {
+	return auth.ClientInfo{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
+// checkNotDisabled writes a 403 response and returns false if the account has been disabled
+func checkNotDisabled(c *gin.Context, user *models.User) bool {
+	if user.Disabled {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is disabled"})
+		return false
+	}
+	return true
+}
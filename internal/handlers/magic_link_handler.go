@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/ecommerce/configs"
+	"github.com/yourusername/ecommerce/internal/audit"
+	"github.com/yourusername/ecommerce/internal/auth"
+	"github.com/yourusername/ecommerce/internal/database"
+	"github.com/yourusername/ecommerce/internal/mailer"
+	"github.com/yourusername/ecommerce/internal/models"
+	"github.com/yourusername/ecommerce/internal/throttle"
+)
+
+// MagicLinkHandler handles passwordless login through emailed links
+type MagicLinkHandler struct {
+	config     *configs.Config
+	mailer     mailer.Mailer
+	loginGuard *throttle.LoginGuard
+}
+
+// NewMagicLinkHandler creates a new magic link handler
+func NewMagicLinkHandler(config *configs.Config, loginGuard *throttle.LoginGuard) *MagicLinkHandler {
+	return &MagicLinkHandler{
+		config:     config,
+		mailer:     mailer.NewMailer(config),
+		loginGuard: loginGuard,
+	}
+}
+
+// RequestLink emails a single-use login link. The response is the same
+// whether or not the email belongs to an account.
+func (h *MagicLinkHandler) RequestLink(c *gin.Context) {
+	var linkData struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+
+	if !bindJSONWithFieldErrors(c, &linkData) {
+		return
+	}
+
+	// Limit per email, whether or not it has an account, so the limit gives nothing away
+	wait, err := h.loginGuard.WaitMagicLink(linkData.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check login link requests"})
+		return
+	}
+	if wait > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many login links requested, please try again later"})
+		return
+	}
+	if err := h.loginGuard.SentMagicLink(linkData.Email); err != nil {
+		log.Printf("Failed to record login link request: %v", err)
+	}
+
+	var user models.User
+	result := database.GetDB().Where("email = ?", linkData.Email).Limit(1).Find(&user)
+	if result.Error == nil && result.RowsAffected > 0 && !user.Disabled {
+		token, err := auth.GeneratePurposeToken(auth.PurposeMagicLink, user.ID, user.Email, h.config.MagicLinkTTL, h.config)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create login link"})
+			return
+		}
+
+		link := h.config.AppBaseURL + "/magic-login?token=" + url.QueryEscape(token)
+		go func(email string) {
+			err := h.mailer.Send(mailer.Message{
+				To:      email,
+				Subject: "Your login link",
+				Body: "Use the link below to log in. It can be used once and expires in " + h.config.MagicLinkTTL.String() + ".\n\n" +
+					link + "\n\n" +
+					"If you didn't request this, you can ignore this email.",
+			})
+			if err != nil {
+				log.Printf("Failed to send login link: %v", err)
+			}
+		}(user.Email)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "If an account exists for this email, a login link has been sent"})
+}
+
+// VerifyLink exchanges a login link's token for a session. It is a POST so
+// that mail scanners following the link don't use up the token.
+func (h *MagicLinkHandler) VerifyLink(c *gin.Context) {
+	var verifyData struct {
+		Token string `json:"token" binding:"required"`
+	}
+
+	if !bindJSONWithFieldErrors(c, &verifyData) {
+		return
+	}
+
+	claims, err := auth.ConsumePurposeToken(verifyData.Token, auth.PurposeMagicLink, h.config)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid, expired or already used login link"})
+		return
+	}
+
+	// The link is only valid for the address it was sent to
+	var user models.User
+	if err := database.GetDB().Where("id = ? AND email = ?", claims.UserID, claims.Email).First(&user).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid, expired or already used login link"})
+		return
+	}
+
+	// Opening the link proves the user controls the address
+	if user.EmailVerifiedAt == nil {
+		now := time.Now()
+		if err := database.GetDB().Model(&models.User{}).Where("id = ?", user.ID).Update("email_verified_at", now).Error; err != nil {
+			log.Printf("Failed to mark email verified: %v", err)
+		} else {
+			user.EmailVerifiedAt = &now
+		}
+	}
+
+	audit.Record(c, audit.Event{
+		ActorID:    &user.ID,
+		Action:     "auth.magic_link.used",
+		TargetType: "user",
+		TargetID:   audit.ID(user.ID),
+	})
+
+	completeLogin(c, &user, h.config)
+}
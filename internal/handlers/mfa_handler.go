@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/ecommerce/configs"
+	"github.com/yourusername/ecommerce/internal/audit"
+	"github.com/yourusername/ecommerce/internal/auth"
+	"github.com/yourusername/ecommerce/internal/database"
+	"github.com/yourusername/ecommerce/internal/models"
+	"github.com/yourusername/ecommerce/internal/throttle"
+	"github.com/yourusername/ecommerce/internal/totp"
+)
+
+// MFAHandler handles two-factor enrollment requests
+type MFAHandler struct {
+	config     *configs.Config
+	loginGuard *throttle.LoginGuard
+}
+
+// NewMFAHandler creates a new MFA handler
+func NewMFAHandler(config *configs.Config, loginGuard *throttle.LoginGuard) *MFAHandler {
+	return &MFAHandler{
+		config:     config,
+		loginGuard: loginGuard,
+	}
+}
+
+// Enroll generates a new TOTP secret for the current user. Two-factor is not
+// enabled until a code from the secret is confirmed with Verify.
+func (h *MFAHandler) Enroll(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var user models.User
+	if err := database.GetDB().First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if user.MFAEnabled() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+		return
+	}
+
+	if err := database.GetDB().Model(&models.User{}).Where("id = ?", user.ID).
+		Updates(map[string]interface{}{"totp_secret": secret, "totp_last_step": 0}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save secret"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":           secret,
+		"provisioning_uri": totp.ProvisioningURI(secret, h.config.MFAIssuer, user.Email),
+	})
+}
+
+// Verify confirms enrollment with a code from the authenticator app, enables
+// two-factor and returns a set of recovery codes
+func (h *MFAHandler) Verify(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var verifyData struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&verifyData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := database.GetDB().First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if user.MFAEnabled() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	if user.TOTPSecret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor enrollment has not been started"})
+		return
+	}
+
+	step, ok := totp.Validate(user.TOTPSecret, verifyData.Code, time.Now(), user.TOTPLastStep)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid two-factor code"})
+		return
+	}
+
+	if err := database.GetDB().Model(&models.User{}).Where("id = ?", user.ID).
+		Updates(map[string]interface{}{"totp_enabled_at": time.Now(), "totp_last_step": step}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+
+	codes, err := auth.GenerateRecoveryCodes(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+		return
+	}
+
+	audit.Record(c, audit.Event{
+		Action:     "mfa.enabled",
+		TargetType: "user",
+		TargetID:   audit.ID(user.ID),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": codes,
+	})
+}
+
+// RegenerateRecoveryCodes replaces the current user's recovery codes
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var regenerateData struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&regenerateData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := database.GetDB().First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if !user.MFAEnabled() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+
+	if !checkLoginThrottle(c, h.loginGuard, user.Email) {
+		return
+	}
+
+	if !h.checkSecondFactor(c, &user, regenerateData.Code, "") {
+		return
+	}
+
+	codes, err := auth.GenerateRecoveryCodes(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recovery codes"})
+		return
+	}
+
+	audit.Record(c, audit.Event{
+		Action:     "mfa.recovery_codes.regenerated",
+		TargetType: "user",
+		TargetID:   audit.ID(user.ID),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// Disable turns two-factor off. It requires the password and a current code
+// or recovery code.
+func (h *MFAHandler) Disable(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var disableData struct {
+		Password     string `json:"password" binding:"required"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+
+	if err := c.ShouldBindJSON(&disableData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := database.GetDB().First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if !user.MFAEnabled() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+
+	// Wrong passwords and codes count towards the login lockout, so a stolen
+	// session can't be used to guess them
+	if !checkLoginThrottle(c, h.loginGuard, user.Email) {
+		return
+	}
+
+	if err := user.ComparePassword(disableData.Password); err != nil {
+		recordLoginFailure(c, h.loginGuard, user.Email)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		return
+	}
+
+	if !h.checkSecondFactor(c, &user, disableData.Code, disableData.RecoveryCode) {
+		return
+	}
+
+	if err := database.GetDB().Model(&models.User{}).Where("id = ?", user.ID).
+		Updates(map[string]interface{}{"totp_secret": "", "totp_enabled_at": nil, "totp_last_step": 0}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+
+	if err := database.GetDB().Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete recovery codes"})
+		return
+	}
+
+	audit.Record(c, audit.Event{
+		Action:     "mfa.disabled",
+		TargetType: "user",
+		TargetID:   audit.ID(user.ID),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// checkSecondFactor verifies a code or recovery code, writing the error
+// response on failure. Wrong codes count towards the login lockout.
+func (h *MFAHandler) checkSecondFactor(c *gin.Context, user *models.User, code, recoveryCode string) bool {
+	if err := auth.VerifySecondFactor(user, code, recoveryCode); err != nil {
+		if errors.Is(err, auth.ErrInvalidMFACode) {
+			recordLoginFailure(c, h.loginGuard, user.Email)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid two-factor code"})
+			return false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify two-factor code"})
+		return false
+	}
+	return true
+}
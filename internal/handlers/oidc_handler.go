@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/ecommerce/configs"
+	"github.com/yourusername/ecommerce/internal/auth"
+	"github.com/yourusername/ecommerce/internal/database"
+	"github.com/yourusername/ecommerce/internal/models"
+	"github.com/yourusername/ecommerce/internal/oidc"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OIDCHandler handles login through external OpenID Connect providers
+type OIDCHandler struct {
+	config    *configs.Config
+	providers map[string]*oidc.Provider
+}
+
+// NewOIDCHandler creates a new OIDC handler
+func NewOIDCHandler(config *configs.Config) *OIDCHandler {
+	return &OIDCHandler{
+		config:    config,
+		providers: oidc.NewProviders(config),
+	}
+}
+
+// GetProviders lists the configured login providers
+func (h *OIDCHandler) GetProviders(c *gin.Context) {
+	names := make([]string, 0, len(h.providers))
+	for name := range h.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	c.JSON(http.StatusOK, gin.H{"providers": names})
+}
+
+// Login starts the authorization code flow with PKCE and redirects to the provider
+func (h *OIDCHandler) Login(c *gin.Context) {
+	provider, ok := h.providers[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown login provider"})
+		return
+	}
+
+	state, err1 := oidc.NewRandomString()
+	nonce, err2 := oidc.NewRandomString()
+	codeVerifier, err3 := oidc.NewRandomString()
+	if err1 != nil || err2 != nil || err3 != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(c.Request.Context(), state, nonce, oidc.CodeChallenge(codeVerifier))
+	if err != nil {
+		log.Printf("OIDC provider %s unavailable: %v", provider.Name(), err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Login provider is unavailable"})
+		return
+	}
+
+	oauthState := models.OAuthState{
+		StateHash:    auth.HashToken(state),
+		Provider:     provider.Name(),
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ExpiresAt:    time.Now().Add(h.config.OIDCStateTTL),
+	}
+	if err := database.GetDB().Create(&oauthState).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+		return
+	}
+
+	h.setStateCookie(c, provider.Name(), state, h.config.OIDCStateTTL)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback completes the flow: it checks the state, redeems the code, verifies
+// the ID token and signs in the linked user
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	provider, ok := h.providers[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown login provider"})
+		return
+	}
+
+	if errCode := c.Query("error"); errCode != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Login was not completed: " + errCode})
+		return
+	}
+
+	state := c.Query("state")
+	code := c.Query("code")
+	if state == "" || code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "State and code are required"})
+		return
+	}
+
+	// The state must come back to the browser that started the login
+	cookieState, _ := c.Cookie(oidc.StateCookie)
+	h.setStateCookie(c, provider.Name(), "", -1)
+
+	// The state is single-use: delete it as it is read
+	var oauthState models.OAuthState
+	result := database.GetDB().Clauses(clause.Returning{}).
+		Where("state_hash = ? AND provider = ?", auth.HashToken(state), provider.Name()).
+		Delete(&oauthState)
+	if result.Error != nil || result.RowsAffected == 0 ||
+		oidc.CheckState(state, cookieState, oauthState.ExpiresAt, time.Now()) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired login state"})
+		return
+	}
+
+	claims, err := provider.Exchange(c.Request.Context(), code, oauthState.CodeVerifier, oauthState.Nonce)
+	if err != nil {
+		log.Printf("OIDC login with %s failed: %v", provider.Name(), err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Could not verify login with provider"})
+		return
+	}
+
+	user, err := h.findOrCreateUser(provider.Name(), claims)
+	if err != nil {
+		if errors.Is(err, oidc.ErrUnverifiedEmail) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "The provider did not return a verified email address"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return
+	}
+
+	completeLogin(c, user, h.config)
+}
+
+// setStateCookie stores the login state in a short-lived cookie sent only to
+// the provider's login and callback routes; a negative maxAge deletes it.
+// It is always SameSite=Lax so it survives the redirect back from the provider.
+func (h *OIDCHandler) setStateCookie(c *gin.Context, providerName, state string, maxAge time.Duration) {
+	cookie := &http.Cookie{
+		Name:     oidc.StateCookie,
+		Value:    state,
+		Path:     "/api/auth/oidc/" + providerName,
+		Domain:   h.config.CookieDomain,
+		MaxAge:   int(maxAge.Seconds()),
+		Secure:   h.config.CookieSecure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if maxAge < 0 {
+		cookie.MaxAge = -1
+	}
+	http.SetCookie(c.Writer, cookie)
+}
+
+// findOrCreateUser resolves the user for a provider identity. Unknown
+// identities are linked to an existing user with the same verified email, or
+// a new user is created.
+func (h *OIDCHandler) findOrCreateUser(providerName string, claims *oidc.IDTokenClaims) (*models.User, error) {
+	var user models.User
+	revokeSessions := false
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var identity models.UserIdentity
+		result := tx.Where("provider = ? AND subject = ?", providerName, claims.Subject).Limit(1).Find(&identity)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			return tx.First(&user, identity.UserID).Error
+		}
+
+		// Only a verified email may be used to link or create an account
+		email, err := oidc.VerifiedEmail(claims)
+		if err != nil {
+			return err
+		}
+
+		result = tx.Where("LOWER(email) = ?", email).Limit(1).Find(&user)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		now := time.Now()
+		if result.RowsAffected == 0 {
+			password, err := auth.NewOpaqueToken()
+			if err != nil {
+				return err
+			}
+			user = models.User{
+				Email:           email,
+				FirstName:       claims.GivenName,
+				LastName:        claims.FamilyName,
+				Role:            "user",
+				EmailVerifiedAt: &now,
+			}
+			if err := user.SetPassword(password); err != nil {
+				return err
+			}
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+		} else if user.EmailVerifiedAt == nil {
+			// Whoever registered the unverified account may not own the
+			// address, so their password and sessions stop working
+			password, err := auth.NewOpaqueToken()
+			if err != nil {
+				return err
+			}
+			if err := user.SetPassword(password); err != nil {
+				return err
+			}
+			user.EmailVerifiedAt = &now
+			if err := tx.Save(&user).Error; err != nil {
+				return err
+			}
+			revokeSessions = true
+		}
+
+		return tx.Create(&models.UserIdentity{
+			UserID:   user.ID,
+			Provider: providerName,
+			Subject:  claims.Subject,
+			Email:    email,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if revokeSessions {
+		if err := auth.RevokeUserTokens(user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return &user, nil
+}
@@ -1,30 +1,125 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/yourusername/ecommerce/configs"
+	"github.com/yourusername/ecommerce/internal/catalog"
 	"github.com/yourusername/ecommerce/internal/database"
 	"github.com/yourusername/ecommerce/internal/models"
+	"github.com/yourusername/ecommerce/internal/pagination"
 )
 
 // OrderHandler handles order-related requests
-type OrderHandler struct{}
+type OrderHandler struct {
+	config  *configs.Config
+	cursors *pagination.Signer
+}
 
 // NewOrderHandler creates a new order handler
-func NewOrderHandler() *OrderHandler {
-	return &OrderHandler{}
+func NewOrderHandler(config *configs.Config) *OrderHandler {
+	return &OrderHandler{
+		config:  config,
+		cursors: pagination.NewSigner(config.CursorSecret),
+	}
 }
 
-// GetOrders returns all orders for the current user
+// orderKeyset lists orders newest first
+var orderKeyset = pagination.Keyset{
+	{Column: "orders.created_at", Descending: true, Type: pagination.KeyTime},
+	{Column: "orders.id", Descending: true, Type: pagination.KeyInt},
+}
+
+// orderSort names orderKeyset in cursors
+const orderSort = "newest"
+
+// GetOrders returns the current user's orders, newest first, a page at a
+// time. Pages are keyset paginated with cursor unless page is given.
 func (h *OrderHandler) GetOrders(c *gin.Context) {
 	userID, _ := c.Get("userID")
 
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	query := database.GetDB().Model(&models.Order{}).Where("user_id = ?", userID)
+
+	if _, offsetMode := c.GetQuery("page"); offsetMode {
+		page, _ := strconv.Atoi(c.Query("page"))
+		if page < 1 {
+			page = 1
+		}
+
+		var count int64
+		query.Count(&count)
+
+		var orders []models.Order
+		query.Preload("OrderItems.Product").Preload("ShippingInfo").
+			Order(orderKeyset.Order(false)).Offset((page - 1) * limit).Limit(limit).Find(&orders)
+
+		setOffsetLinks(c, page, limit, count)
+
+		c.JSON(http.StatusOK, gin.H{
+			"orders": orders,
+			"total":  count,
+			"page":   page,
+			"limit":  limit,
+		})
+		return
+	}
+
+	cursor, ok := readCursor(c, h.cursors, orderSort)
+	if !ok {
+		return
+	}
+
+	response := gin.H{"limit": limit}
+	if c.Query("include_total") == "true" {
+		var count int64
+		query.Count(&count)
+		response["total"] = count
+		c.Header("X-Total-Count", strconv.FormatInt(count, 10))
+	}
+
+	if cursor != nil {
+		condition, args, err := orderKeyset.Seek(*cursor)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor; start again from the first page"})
+			return
+		}
+		query = query.Where(condition, args...)
+	}
+
 	var orders []models.Order
-	database.GetDB().Where("user_id = ?", userID).Preload("OrderItems.Product").Preload("ShippingInfo").Find(&orders)
+	if err := query.Preload("OrderItems.Product").Preload("ShippingInfo").
+		Order(orderKeyset.Order(cursor != nil && cursor.Backward)).Limit(limit + 1).
+		Find(&orders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load orders"})
+		return
+	}
+	orders, hasNext, hasPrev := pagination.Trim(orders, limit, cursor)
 
-	c.JSON(http.StatusOK, gin.H{"orders": orders})
+	var firstValues, lastValues []interface{}
+	if len(orders) > 0 {
+		first, last := orders[0], orders[len(orders)-1]
+		firstValues = []interface{}{first.CreatedAt, first.ID}
+		lastValues = []interface{}{last.CreatedAt, last.ID}
+	}
+
+	navigation, err := newCursorPage(c, h.cursors, orderKeyset, orderSort, firstValues, lastValues, hasNext, hasPrev)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load orders"})
+		return
+	}
+
+	response["orders"] = orders
+	response["next_cursor"] = navigation.Next
+	response["prev_cursor"] = navigation.Prev
+	c.JSON(http.StatusOK, response)
 }
 
 // GetOrder returns a specific order
@@ -41,13 +136,74 @@ func (h *OrderHandler) GetOrder(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"order": order})
 }
 
+// ListAllOrders returns orders across all customers, optionally filtered by user and status
+func (h *OrderHandler) ListAllOrders(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	query := database.GetDB().Model(&models.Order{})
+	if userID := c.Query("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var count int64
+	query.Count(&count)
+
+	var orders []models.Order
+	query.Preload("OrderItems.Product").Preload("ShippingInfo").
+		Order("created_at DESC").Offset((page - 1) * limit).Limit(limit).Find(&orders)
+
+	c.JSON(http.StatusOK, gin.H{
+		"orders": orders,
+		"total":  count,
+		"page":   page,
+		"limit":  limit,
+	})
+}
+
+// GetAnyOrder returns a specific order regardless of which customer placed it
+func (h *OrderHandler) GetAnyOrder(c *gin.Context) {
+	id := c.Param("id")
+
+	var order models.Order
+	if err := database.GetDB().Preload("User").Preload("OrderItems.Product").Preload("ShippingInfo").First(&order, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"order": order})
+}
+
 // CreateOrder creates a new order
 func (h *OrderHandler) CreateOrder(c *gin.Context) {
 	userID, _ := c.Get("userID")
 
+	// Optionally require a verified email before accepting orders
+	if h.config.RequireVerifiedEmailForOrders {
+		var user models.User
+		if err := database.GetDB().First(&user, userID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		if user.EmailVerifiedAt == nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Email address must be verified before placing orders"})
+			return
+		}
+	}
+
 	var orderData struct {
-		OrderItems   []struct {
+		OrderItems []struct {
 			ProductID uint `json:"product_id"`
+			VariantID uint `json:"variant_id"` // may be left out for products without options
 			Quantity  int  `json:"quantity"`
 		} `json:"order_items"`
 		ShippingInfo struct {
@@ -100,6 +256,12 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 	// Create order items and calculate total
 	var totalAmount float64
 	for _, item := range orderData.OrderItems {
+		if item.Quantity < 1 {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Quantity must be at least 1"})
+			return
+		}
+
 		var product models.Product
 		if err := tx.First(&product, item.ProductID).Error; err != nil {
 			tx.Rollback()
@@ -107,27 +269,50 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 			return
 		}
 
-		// Check if enough stock
-		if product.Stock < item.Quantity {
+		// Find the variant; a product without options has just one
+		var variants []models.Variant
+		variantQuery := tx.Where("product_id = ?", product.ID)
+		if item.VariantID != 0 {
+			variantQuery = variantQuery.Where("id = ?", item.VariantID)
+		}
+		if err := variantQuery.Limit(2).Find(&variants).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load product variants"})
+			return
+		}
+		if len(variants) == 0 {
 			tx.Rollback()
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Not enough stock for product: " + product.Name})
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Variant not found: " + strconv.Itoa(int(item.VariantID))})
 			return
 		}
+		if len(variants) > 1 {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{"error": "variant_id is required for product: " + product.Name})
+			return
+		}
+		variant := variants[0]
 
-		// Update stock
-		product.Stock -= item.Quantity
-		if err := tx.Save(&product).Error; err != nil {
+		// Take the stock, failing if another order got to it first
+		if err := catalog.ReserveStock(tx, &variant, item.Quantity); err != nil {
 			tx.Rollback()
+			if errors.Is(err, catalog.ErrOutOfStock) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Not enough stock for product: " + product.Name})
+				return
+			}
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update product stock"})
 			return
 		}
 
 		// Create order item
+		price := variant.PriceFor(product)
 		orderItem := models.OrderItem{
 			OrderID:   order.ID,
 			ProductID: item.ProductID,
+			VariantID: &variant.ID,
+			SKU:       variant.SKU,
+			Title:     variant.Title,
 			Quantity:  item.Quantity,
-			Price:     product.Price,
+			Price:     price,
 		}
 
 		if err := tx.Create(&orderItem).Error; err != nil {
@@ -136,7 +321,7 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 			return
 		}
 
-		totalAmount += product.Price * float64(item.Quantity)
+		totalAmount += price * float64(item.Quantity)
 	}
 
 	// Update order with total amount
@@ -155,4 +340,4 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 	database.GetDB().Preload("OrderItems.Product").Preload("ShippingInfo").First(&createdOrder, order.ID)
 
 	c.JSON(http.StatusCreated, gin.H{"order": createdOrder})
-}
\ No newline at end of file
+}
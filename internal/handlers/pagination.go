@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/ecommerce/internal/pagination"
+)
+
+// readCursor decodes the cursor query parameter, writing a 400 response if it
+// is invalid. It returns nil on the first page.
+func readCursor(c *gin.Context, signer *pagination.Signer, sort string) (*pagination.Cursor, bool) {
+	token := c.Query("cursor")
+	if token == "" {
+		return nil, true
+	}
+
+	cursor, err := signer.Decode(token, sort)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor; start again from the first page"})
+		return nil, false
+	}
+	return &cursor, true
+}
+
+// cursorPage is the navigation for one page of a keyset-paginated listing
+type cursorPage struct {
+	Next string `json:"next_cursor,omitempty"`
+	Prev string `json:"prev_cursor,omitempty"`
+}
+
+// newCursorPage builds next and prev cursors from the sort key values of
+// the page's first and last rows and sets the matching Link header
+func newCursorPage(c *gin.Context, signer *pagination.Signer, keyset pagination.Keyset, sort string,
+	firstValues, lastValues []interface{}, hasNext, hasPrev bool) (cursorPage, error) {
+	var page cursorPage
+	var links []pagination.Link
+
+	if hasNext && lastValues != nil {
+		cursor, err := keyset.Cursor(sort, lastValues, false)
+		if err != nil {
+			return page, err
+		}
+		if page.Next, err = signer.Encode(cursor); err != nil {
+			return page, err
+		}
+		links = append(links, pagination.Link{Rel: "next", Params: map[string]string{"cursor": page.Next}})
+	}
+
+	if hasPrev && firstValues != nil {
+		cursor, err := keyset.Cursor(sort, firstValues, true)
+		if err != nil {
+			return page, err
+		}
+		if page.Prev, err = signer.Encode(cursor); err != nil {
+			return page, err
+		}
+		links = append(links, pagination.Link{Rel: "prev", Params: map[string]string{"cursor": page.Prev}})
+	}
+
+	if hasPrev {
+		links = append(links, pagination.Link{Rel: "first", Params: map[string]string{"cursor": "", "pagination": "cursor"}})
+	}
+
+	pagination.SetLinkHeader(c, links)
+	return page, nil
+}
+
+// setOffsetLinks sets the Link and X-Total-Count headers for an offset-paginated listing
+func setOffsetLinks(c *gin.Context, page, limit int, total int64) {
+	lastPage := int((total + int64(limit) - 1) / int64(limit))
+	if lastPage < 1 {
+		lastPage = 1
+	}
+
+	links := []pagination.Link{
+		{Rel: "first", Params: map[string]string{"page": "1"}},
+		{Rel: "last", Params: map[string]string{"page": strconv.Itoa(lastPage)}},
+	}
+	if page > 1 {
+		links = append(links, pagination.Link{Rel: "prev", Params: map[string]string{"page": strconv.Itoa(page - 1)}})
+	}
+	if page < lastPage {
+		links = append(links, pagination.Link{Rel: "next", Params: map[string]string{"page": strconv.Itoa(page + 1)}})
+	}
+
+	pagination.SetLinkHeader(c, links)
+	c.Header("X-Total-Count", strconv.FormatInt(total, 10))
+}
@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/ecommerce/internal/models"
+	"github.com/yourusername/ecommerce/internal/pagination"
+	"gorm.io/gorm"
+)
+
+// productFilter narrows a product listing
+type productFilter struct {
+	CategoryID   uint // includes the category's descendants
+	MinPrice     *float64
+	MaxPrice     *float64
+	InStock      bool
+	CreatedAfter *time.Time
+}
+
+// Facets a filter can leave out, so each facet counts products as if its own
+// filter weren't applied
+const (
+	facetCategory = "category"
+	facetPrice    = "price"
+)
+
+// priceBucketEdges are the lower bounds of the price facet's buckets; the last
+// bucket has no upper bound
+var priceBucketEdges = []float64{0, 25, 50, 100, 250, 500}
+
+// listingPrice is the price a product is listed at: the lowest price of its
+// variants, so one whose variants cost 20 to 30 is filtered, sorted and
+// counted in the price facet as 20. Variants without their own price cost the
+// product's price.
+const listingPrice = `COALESCE((SELECT MIN(COALESCE(variants.price, products.price))
+	FROM variants WHERE variants.product_id = products.id), products.price)`
+
+// derivedValues are a product's sort values that aren't columns of its row
+type derivedValues struct {
+	unitsSold    int64
+	listingPrice float64
+}
+
+// productSortColumns maps the sort keys clients may use to an SQL expression,
+// whether it sorts descending by default and how to read a product's value
+// for a cursor. Prefixing a key with - reverses it.
+var productSortColumns = map[string]struct {
+	expression string
+	descending bool
+	keyType    pagination.KeyType
+	value      func(product models.Product, derived derivedValues) interface{}
+}{
+	"price":      {listingPrice, false, pagination.KeyFloat, func(_ models.Product, d derivedValues) interface{} { return d.listingPrice }},
+	"name":       {"products.name", false, pagination.KeyString, func(p models.Product, _ derivedValues) interface{} { return p.Name }},
+	"newest":     {"products.created_at", true, pagination.KeyTime, func(p models.Product, _ derivedValues) interface{} { return p.CreatedAt }},
+	"popularity": {"COALESCE(sales.units_sold, 0)", true, pagination.KeyInt, func(_ models.Product, d derivedValues) interface{} { return d.unitsSold }},
+}
+
+// productSort is a parsed sort parameter
+type productSort struct {
+	key        string // normalized sort parameter, e.g. "-price,name"
+	names      []string
+	keyset     pagination.Keyset // ends with products.id
+	needsSales bool              // sorting by popularity needs unitsSoldJoin
+	needsPrice bool              // cursors for price sorts need the listing price
+}
+
+// unitsSoldJoin adds each product's units sold on orders that weren't cancelled, for sorting by popularity
+const unitsSoldJoin = `LEFT JOIN (
+	SELECT order_items.product_id, SUM(order_items.quantity) AS units_sold
+	FROM order_items JOIN orders ON orders.id = order_items.order_id
+	WHERE orders.status <> 'cancelled'
+	GROUP BY order_items.product_id
+) sales ON sales.product_id = products.id`
+
+// categoryDescendants selects a category's ID and the IDs of every category
+// below it, whose paths start with its own
+const categoryDescendants = `SELECT id FROM categories
+	WHERE path LIKE (SELECT path FROM categories WHERE id = ?) || '%'`
+
+// parseProductFilter reads the filter query parameters, writing a 400
+// response if any are malformed
+func parseProductFilter(c *gin.Context) (productFilter, bool) {
+	var filter productFilter
+
+	if value := c.Query("category_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category_id"})
+			return filter, false
+		}
+		filter.CategoryID = uint(id)
+	}
+
+	for param, target := range map[string]**float64{"min_price": &filter.MinPrice, "max_price": &filter.MaxPrice} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		price, err := strconv.ParseFloat(value, 64)
+		if err != nil || price < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param + ", expected a non-negative number"})
+			return filter, false
+		}
+		*target = &price
+	}
+	if filter.MinPrice != nil && filter.MaxPrice != nil && *filter.MinPrice > *filter.MaxPrice {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "min_price must not be greater than max_price"})
+		return filter, false
+	}
+
+	if value := c.Query("in_stock"); value != "" {
+		inStock, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid in_stock, expected true or false"})
+			return filter, false
+		}
+		filter.InStock = inStock
+	}
+
+	if value := c.Query("created_after"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid created_after time, expected RFC 3339"})
+			return filter, false
+		}
+		filter.CreatedAfter = &t
+	}
+
+	return filter, true
+}
+
+// apply adds the filter's conditions to a products query, leaving out the
+// filter behind the named facet if skip is set
+func (f productFilter) apply(query *gorm.DB, skip string) *gorm.DB {
+	if f.CategoryID != 0 && skip != facetCategory {
+		query = query.Where("products.category_id IN ("+categoryDescendants+")", f.CategoryID)
+	}
+	if skip != facetPrice {
+		if f.MinPrice != nil {
+			query = query.Where(listingPrice+" >= ?", *f.MinPrice)
+		}
+		if f.MaxPrice != nil {
+			query = query.Where(listingPrice+" <= ?", *f.MaxPrice)
+		}
+	}
+	// A product's stock is the total of its variants'
+	if f.InStock {
+		query = query.Where("products.stock > 0")
+	}
+	if f.CreatedAfter != nil {
+		query = query.Where("products.created_at > ?", *f.CreatedAfter)
+	}
+	return query
+}
+
+// parseProductSort reads a comma-separated list of sort keys such as
+// "price,-newest", writing a 400 response for unknown keys. The product ID
+// is always added as a final tie-breaker.
+func parseProductSort(c *gin.Context) (productSort, bool) {
+	var sort productSort
+	var normalized []string
+
+	for _, key := range strings.Split(c.DefaultQuery("sort", "newest"), ",") {
+		key = strings.TrimSpace(key)
+		reverse := strings.HasPrefix(key, "-")
+		name := strings.TrimPrefix(key, "-")
+
+		column, ok := productSortColumns[name]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sort key: " + name + "; use price, name, newest or popularity"})
+			return sort, false
+		}
+		switch name {
+		case "popularity":
+			sort.needsSales = true
+		case "price":
+			sort.needsPrice = true
+		}
+
+		normalized = append(normalized, key)
+		sort.names = append(sort.names, name)
+		sort.keyset = append(sort.keyset, pagination.Key{
+			Column:     column.expression,
+			Descending: column.descending != reverse,
+			Type:       column.keyType,
+		})
+	}
+
+	sort.key = strings.Join(normalized, ",")
+	sort.keyset = append(sort.keyset, pagination.Key{Column: "products.id", Type: pagination.KeyInt})
+	return sort, true
+}
+
+// cursorValues returns a product's values for each key of the sort order
+func (s productSort) cursorValues(db *gorm.DB, product models.Product) ([]interface{}, error) {
+	var derived derivedValues
+	if s.needsSales {
+		err := db.Table("order_items").
+			Joins("JOIN orders ON orders.id = order_items.order_id").
+			Where("order_items.product_id = ? AND orders.status <> ?", product.ID, "cancelled").
+			Select("COALESCE(SUM(order_items.quantity), 0)").
+			Scan(&derived.unitsSold).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	if s.needsPrice {
+		err := db.Table("products").Where("products.id = ?", product.ID).
+			Select(listingPrice).
+			Scan(&derived.listingPrice).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	values := make([]interface{}, 0, len(s.keyset))
+	for _, name := range s.names {
+		values = append(values, productSortColumns[name].value(product, derived))
+	}
+	return append(values, product.ID), nil
+}
+
+// categoryFacet is the number of matching products directly in one category
+type categoryFacet struct {
+	ID       uint   `json:"id"`
+	Name     string `json:"name"`
+	ParentID *uint  `json:"parent_id"`
+	Count    int64  `json:"count"`
+}
+
+// priceFacet is the number of matching products in a price range; Max is
+// exclusive and nil for the top bucket
+type priceFacet struct {
+	Min   float64  `json:"min"`
+	Max   *float64 `json:"max"`
+	Count int64    `json:"count"`
+}
+
+// productFacets counts the products matching a filter by category and by
+// price bucket
+func productFacets(db *gorm.DB, filter productFilter) (gin.H, error) {
+	categories := []categoryFacet{}
+	err := filter.apply(db.Table("products"), facetCategory).
+		Select("categories.id, categories.name, categories.parent_id, COUNT(products.id) AS count").
+		Joins("JOIN categories ON categories.id = products.category_id").
+		Group("categories.id, categories.name, categories.parent_id").
+		Order("categories.name").
+		Scan(&categories).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var selects []string
+	var args []interface{}
+	for i, min := range priceBucketEdges {
+		if i+1 < len(priceBucketEdges) {
+			selects = append(selects, "COUNT(*) FILTER (WHERE listing_price >= ? AND listing_price < ?)")
+			args = append(args, min, priceBucketEdges[i+1])
+		} else {
+			selects = append(selects, "COUNT(*) FILTER (WHERE listing_price >= ?)")
+			args = append(args, min)
+		}
+	}
+
+	counts := make([]int64, len(priceBucketEdges))
+	dest := make([]interface{}, len(counts))
+	for i := range counts {
+		dest[i] = &counts[i]
+	}
+	matching := filter.apply(db.Table("products"), facetPrice).
+		Select(listingPrice + " AS listing_price")
+	row := db.Table("(?) AS matching", matching).
+		Select(strings.Join(selects, ", "), args...).
+		Row()
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	prices := make([]priceFacet, len(priceBucketEdges))
+	for i, min := range priceBucketEdges {
+		prices[i] = priceFacet{Min: min, Count: counts[i]}
+		if i+1 < len(priceBucketEdges) {
+			max := priceBucketEdges[i+1]
+			prices[i].Max = &max
+		}
+	}
+
+	return gin.H{"categories": categories, "price": prices}, nil
+}
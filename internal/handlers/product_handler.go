@@ -5,48 +5,166 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/yourusername/ecommerce/configs"
+	"github.com/yourusername/ecommerce/internal/audit"
+	"github.com/yourusername/ecommerce/internal/catalog"
 	"github.com/yourusername/ecommerce/internal/database"
 	"github.com/yourusername/ecommerce/internal/models"
+	"github.com/yourusername/ecommerce/internal/pagination"
+	"gorm.io/gorm"
 )
 
 // ProductHandler handles product-related requests
-type ProductHandler struct{}
+type ProductHandler struct {
+	cursors *pagination.Signer
+}
 
 // NewProductHandler creates a new product handler
-func NewProductHandler() *ProductHandler {
-	return &ProductHandler{}
+func NewProductHandler(config *configs.Config) *ProductHandler {
+	return &ProductHandler{cursors: pagination.NewSigner(config.CursorSecret)}
 }
 
-// GetProducts returns all products
+// GetProducts returns products matching the filters in the query string,
+// sorted and paginated, with facet counts for building a filter sidebar.
+// Passing cursor (or pagination=cursor for the first page) switches from
+// page numbers to keyset pagination.
 func (h *ProductHandler) GetProducts(c *gin.Context) {
-	var products []models.Product
-	
 	// Get query parameters for pagination
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-	offset := (page - 1) * limit
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
 
-	// Get products with pagination
-	database.GetDB().Preload("Category").Preload("Images").Offset(offset).Limit(limit).Find(&products)
+	filter, ok := parseProductFilter(c)
+	if !ok {
+		return
+	}
+	sort, ok := parseProductSort(c)
+	if !ok {
+		return
+	}
+
+	db := database.GetDB()
+	query := filter.apply(db.Model(&models.Product{}), "")
+	if sort.needsSales {
+		query = query.Joins(unitsSoldJoin)
+	}
+
+	facets, err := productFacets(db, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load products"})
+		return
+	}
 
-	// Count total products
+	_, cursorMode := c.GetQuery("cursor")
+	if cursorMode || c.Query("pagination") == "cursor" {
+		h.getProductsByCursor(c, query, filter, sort, limit, facets)
+		return
+	}
+
+	// Count matching products
 	var count int64
-	database.GetDB().Model(&models.Product{}).Count(&count)
+	if err := filter.apply(db.Model(&models.Product{}), "").Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load products"})
+		return
+	}
+
+	// Get products with pagination
+	var products []models.Product
+	if err := query.Preload("Category").Preload("Images").
+		Order(sort.keyset.Order(false)).Offset((page - 1) * limit).Limit(limit).
+		Find(&products).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load products"})
+		return
+	}
+
+	setOffsetLinks(c, page, limit, count)
 
 	c.JSON(http.StatusOK, gin.H{
 		"products": products,
 		"total":    count,
 		"page":     page,
 		"limit":    limit,
+		"facets":   facets,
 	})
 }
 
+// getProductsByCursor writes one keyset-paginated page of products. The
+// total is only counted when include_total=true.
+func (h *ProductHandler) getProductsByCursor(c *gin.Context, query *gorm.DB, filter productFilter, sort productSort, limit int, facets gin.H) {
+	db := database.GetDB()
+
+	cursor, ok := readCursor(c, h.cursors, sort.key)
+	if !ok {
+		return
+	}
+	if cursor != nil {
+		condition, args, err := sort.keyset.Seek(*cursor)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor; start again from the first page"})
+			return
+		}
+		query = query.Where(condition, args...)
+	}
+
+	var products []models.Product
+	if err := query.Preload("Category").Preload("Images").
+		Order(sort.keyset.Order(cursor != nil && cursor.Backward)).Limit(limit + 1).
+		Find(&products).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load products"})
+		return
+	}
+	products, hasNext, hasPrev := pagination.Trim(products, limit, cursor)
+
+	var firstValues, lastValues []interface{}
+	if len(products) > 0 {
+		var err error
+		if firstValues, err = sort.cursorValues(db, products[0]); err == nil {
+			lastValues, err = sort.cursorValues(db, products[len(products)-1])
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load products"})
+			return
+		}
+	}
+
+	navigation, err := newCursorPage(c, h.cursors, sort.keyset, sort.key, firstValues, lastValues, hasNext, hasPrev)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load products"})
+		return
+	}
+
+	response := gin.H{
+		"products":    products,
+		"limit":       limit,
+		"next_cursor": navigation.Next,
+		"prev_cursor": navigation.Prev,
+		"facets":      facets,
+	}
+
+	if c.Query("include_total") == "true" {
+		var count int64
+		if err := filter.apply(db.Model(&models.Product{}), "").Count(&count).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load products"})
+			return
+		}
+		response["total"] = count
+		c.Header("X-Total-Count", strconv.FormatInt(count, 10))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
 // GetProduct returns a specific product
 func (h *ProductHandler) GetProduct(c *gin.Context) {
 	id := c.Param("id")
 
 	var product models.Product
-	if err := database.GetDB().Preload("Category").Preload("Images").First(&product, id).Error; err != nil {
+	if err := preloadVariants(database.GetDB().Preload("Category").Preload("Images")).First(&product, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
 		return
 	}
@@ -54,19 +172,35 @@ func (h *ProductHandler) GetProduct(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"product": product})
 }
 
-// CreateProduct creates a new product
+// CreateProduct creates a new product with a single variant holding its
+// stock; options are added with GenerateVariants
 func (h *ProductHandler) CreateProduct(c *gin.Context) {
 	var product models.Product
 	if err := c.ShouldBindJSON(&product); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	product.Options = nil
+	product.Variants = nil
 
-	if err := database.GetDB().Create(&product).Error; err != nil {
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&product).Error; err != nil {
+			return err
+		}
+		return catalog.CreateDefaultVariant(tx, &product)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create product"})
 		return
 	}
 
+	audit.Record(c, audit.Event{
+		Action:     "product.created",
+		TargetType: "product",
+		TargetID:   audit.ID(product.ID),
+		Diff:       audit.Changes(nil, product),
+	})
+
 	c.JSON(http.StatusCreated, gin.H{"product": product})
 }
 
@@ -79,17 +213,37 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
 		return
 	}
+	before := product
 
 	if err := c.ShouldBindJSON(&product); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	product.Options = nil
+	product.Variants = nil
 
-	if err := database.GetDB().Save(&product).Error; err != nil {
+	// Stock is kept per variant, so it can only be set here for products without options
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&product).Error; err != nil {
+			return err
+		}
+		if err := catalog.SetProductStock(tx, product.ID, product.Stock); err != nil {
+			return err
+		}
+		return tx.Select("stock").First(&product, product.ID).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update product"})
 		return
 	}
 
+	audit.Record(c, audit.Event{
+		Action:     "product.updated",
+		TargetType: "product",
+		TargetID:   audit.ID(product.ID),
+		Diff:       audit.Changes(before, product),
+	})
+
 	c.JSON(http.StatusOK, gin.H{"product": product})
 }
 
@@ -108,5 +262,12 @@ func (h *ProductHandler) DeleteProduct(c *gin.Context) {
 		return
 	}
 
+	audit.Record(c, audit.Event{
+		Action:     "product.deleted",
+		TargetType: "product",
+		TargetID:   audit.ID(product.ID),
+		Diff:       audit.Changes(product, nil),
+	})
+
 	c.JSON(http.StatusOK, gin.H{"message": "Product deleted successfully"})
 }
\ No newline at end of file
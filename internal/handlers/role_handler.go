@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/ecommerce/internal/audit"
+	"github.com/yourusername/ecommerce/internal/auth"
+	"github.com/yourusername/ecommerce/internal/database"
+	"github.com/yourusername/ecommerce/internal/models"
+	"github.com/yourusername/ecommerce/internal/rbac"
+	"gorm.io/gorm"
+)
+
+// RoleHandler handles role and permission management requests
+type RoleHandler struct{}
+
+// NewRoleHandler creates a new role handler
+func NewRoleHandler() *RoleHandler {
+	return &RoleHandler{}
+}
+
+// GetPermissions returns every permission that can be granted
+func (h *RoleHandler) GetPermissions(c *gin.Context) {
+	var permissions []models.Permission
+	database.GetDB().Order("name").Find(&permissions)
+
+	c.JSON(http.StatusOK, gin.H{"permissions": permissions})
+}
+
+// GetRoles returns all roles with their permissions
+func (h *RoleHandler) GetRoles(c *gin.Context) {
+	var roles []models.Role
+	database.GetDB().Preload("Permissions").Order("name").Find(&roles)
+
+	c.JSON(http.StatusOK, gin.H{"roles": roles})
+}
+
+// CreateRole creates a new role
+func (h *RoleHandler) CreateRole(c *gin.Context) {
+	var roleData struct {
+		Name        string   `json:"name" binding:"required"`
+		Description string   `json:"description"`
+		Permissions []string `json:"permissions"`
+	}
+
+	if err := c.ShouldBindJSON(&roleData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	exists, err := rbac.RoleExists(roleData.Name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create role"})
+		return
+	}
+	if exists {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role with this name already exists"})
+		return
+	}
+
+	permissions, ok := h.lookupPermissions(c, roleData.Permissions)
+	if !ok {
+		return
+	}
+
+	role := models.Role{
+		Name:        roleData.Name,
+		Description: roleData.Description,
+		Permissions: permissions,
+	}
+	if err := database.GetDB().Create(&role).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create role"})
+		return
+	}
+
+	audit.Record(c, audit.Event{
+		Action:     "role.created",
+		TargetType: "role",
+		TargetID:   audit.ID(role.ID),
+		Diff:       audit.Changes(nil, role),
+	})
+
+	c.JSON(http.StatusCreated, gin.H{"role": role})
+}
+
+// UpdateRole replaces a role's description and permissions. Access tokens
+// carry their permissions, so when any are taken away the sessions of users
+// holding the role are ended; added permissions are picked up the next time
+// their access token is issued.
+func (h *RoleHandler) UpdateRole(c *gin.Context) {
+	id := c.Param("id")
+
+	var role models.Role
+	if err := database.GetDB().Preload("Permissions").First(&role, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	}
+	before := role
+
+	if role.Name == rbac.RoleAdmin {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The admin role always has every permission"})
+		return
+	}
+
+	var roleData struct {
+		Description string   `json:"description"`
+		Permissions []string `json:"permissions"`
+	}
+
+	if err := c.ShouldBindJSON(&roleData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	permissions, ok := h.lookupPermissions(c, roleData.Permissions)
+	if !ok {
+		return
+	}
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&role).Update("description", roleData.Description).Error; err != nil {
+			return err
+		}
+		return tx.Model(&role).Association("Permissions").Replace(permissions)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+
+	database.GetDB().Preload("Permissions").First(&role, role.ID)
+
+	audit.Record(c, audit.Event{
+		Action:     "role.updated",
+		TargetType: "role",
+		TargetID:   audit.ID(role.ID),
+		Diff:       audit.Changes(before, role),
+	})
+
+	if removesPermissions(before.Permissions, role.Permissions) {
+		if err := revokeRoleHolderTokens(role.Name); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Role updated but failed to end sessions"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"role": role})
+}
+
+// DeleteRole deletes a role that no user holds
+func (h *RoleHandler) DeleteRole(c *gin.Context) {
+	id := c.Param("id")
+
+	var role models.Role
+	if err := database.GetDB().Preload("Permissions").First(&role, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	}
+
+	if role.Name == rbac.RoleAdmin || role.Name == rbac.RoleUser {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Built-in roles cannot be deleted"})
+		return
+	}
+
+	var holders int64
+	database.GetDB().Model(&models.User{}).Where("role = ?", role.Name).Count(&holders)
+	if holders > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Role is still assigned to users"})
+		return
+	}
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&role).Association("Permissions").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(&role).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete role"})
+		return
+	}
+
+	audit.Record(c, audit.Event{
+		Action:     "role.deleted",
+		TargetType: "role",
+		TargetID:   audit.ID(role.ID),
+		Diff:       audit.Changes(role, nil),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role deleted successfully"})
+}
+
+// removesPermissions reports whether any permission in before is missing from after
+func removesPermissions(before, after []models.Permission) bool {
+	kept := make(map[string]bool, len(after))
+	for _, permission := range after {
+		kept[permission.Name] = true
+	}
+	for _, permission := range before {
+		if !kept[permission.Name] {
+			return true
+		}
+	}
+	return false
+}
+
+// revokeRoleHolderTokens ends the sessions of every user holding a role, so
+// that access tokens carrying the role's old permissions stop working
+func revokeRoleHolderTokens(roleName string) error {
+	var userIDs []uint
+	if err := database.GetDB().Model(&models.User{}).Where("role = ?", roleName).Pluck("id", &userIDs).Error; err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		if err := auth.RevokeUserTokens(userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookupPermissions loads permissions by name, writing a 400 response if any are unknown
+func (h *RoleHandler) lookupPermissions(c *gin.Context, names []string) ([]models.Permission, bool) {
+	permissions := []models.Permission{}
+	for _, name := range names {
+		if !rbac.IsKnownPermission(name) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown permission: " + name})
+			return nil, false
+		}
+	}
+
+	if len(names) > 0 {
+		if err := database.GetDB().Where("name IN ?", names).Find(&permissions).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load permissions"})
+			return nil, false
+		}
+	}
+
+	return permissions, true
+}
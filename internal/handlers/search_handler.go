@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/ecommerce/internal/database"
+	"github.com/yourusername/ecommerce/internal/models"
+	"github.com/yourusername/ecommerce/internal/search"
+)
+
+// SearchHandler handles product search requests
+type SearchHandler struct {
+	engine search.Engine
+}
+
+// NewSearchHandler creates a new search handler
+func NewSearchHandler(engine search.Engine) *SearchHandler {
+	return &SearchHandler{engine: engine}
+}
+
+// searchResult is a matching product with its rank and highlighted text
+type searchResult struct {
+	Product       models.Product `json:"product"`
+	Rank          float64        `json:"rank"`
+	NameHighlight string         `json:"name_highlight"`
+	Snippet       string         `json:"snippet"`
+}
+
+// SearchProducts returns the products best matching the q parameter
+func (h *SearchHandler) SearchProducts(c *gin.Context) {
+	text := strings.TrimSpace(c.Query("q"))
+	if text == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter q is required"})
+		return
+	}
+	if len(text) > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query is too long"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	results, err := h.engine.Search(c.Request.Context(), search.Query{
+		Text:   text,
+		Limit:  limit,
+		Offset: (page - 1) * limit,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed"})
+		return
+	}
+
+	// Load the matched products, keeping the engine's order
+	ids := make([]uint, len(results.Hits))
+	for i, hit := range results.Hits {
+		ids[i] = hit.ProductID
+	}
+	var products []models.Product
+	if len(ids) > 0 {
+		if err := database.GetDB().Preload("Category").Preload("Images").Where("id IN ?", ids).Find(&products).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed"})
+			return
+		}
+	}
+	byID := make(map[uint]models.Product, len(products))
+	for _, product := range products {
+		byID[product.ID] = product
+	}
+
+	items := make([]searchResult, 0, len(results.Hits))
+	for _, hit := range results.Hits {
+		product, ok := byID[hit.ProductID]
+		if !ok {
+			continue // deleted since the engine indexed it
+		}
+		items = append(items, searchResult{
+			Product:       product,
+			Rank:          hit.Rank,
+			NameHighlight: hit.NameHighlight,
+			Snippet:       hit.Snippet,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results": items,
+		"total":   results.Total,
+		"fuzzy":   results.Fuzzy,
+		"page":    page,
+		"limit":   limit,
+	})
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/ecommerce/internal/audit"
+	"github.com/yourusername/ecommerce/internal/auth"
+	"github.com/yourusername/ecommerce/internal/database"
+	"github.com/yourusername/ecommerce/internal/geoip"
+	"github.com/yourusername/ecommerce/internal/models"
+)
+
+// SessionHandler handles requests for a user's logged-in devices
+type SessionHandler struct {
+	geo *geoip.Database
+}
+
+// NewSessionHandler creates a new session handler. geo may be nil, in which
+// case no locations are shown.
+func NewSessionHandler(geo *geoip.Database) *SessionHandler {
+	return &SessionHandler{geo: geo}
+}
+
+// sessionResponse is a session as shown to its owner
+type sessionResponse struct {
+	ID         string    `json:"id"`
+	Device     string    `json:"device"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	Location   string    `json:"location,omitempty"`
+	Current    bool      `json:"current"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// GetSessions lists the user's active sessions, most recently used first
+func (h *SessionHandler) GetSessions(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	sessions, err := auth.ActiveSessions(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load sessions"})
+		return
+	}
+
+	currentID := c.GetString("sessionID")
+	response := make([]sessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		item := sessionResponse{
+			ID:         session.ID,
+			Device:     describeDevice(session.UserAgent),
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			Current:    session.ID == currentID,
+			LastSeenAt: session.LastSeenAt,
+			CreatedAt:  session.CreatedAt,
+		}
+		if location, ok := h.geo.Lookup(session.IP); ok {
+			item.Location = location.String()
+		}
+		response = append(response, item)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": response})
+}
+
+// RevokeSession logs one of the user's devices out
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	id := c.Param("id")
+
+	var session models.Session
+	result := database.GetDB().Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).Limit(1).Find(&session)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load session"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	if err := auth.RevokeFamily(session.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+
+	audit.Record(c, audit.Event{
+		Action:     "session.revoked",
+		TargetType: "session",
+		TargetID:   session.ID,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked successfully"})
+}
+
+// RevokeAllSessions logs every one of the user's devices out, including this one
+func (h *SessionHandler) RevokeAllSessions(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	if err := auth.RevokeUserTokens(userID.(uint)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	// Also end the current access token, which may belong to no session
+	if err := auth.RevokeAccessToken(c.GetString("tokenID"), c.GetTime("tokenExpiresAt")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke token"})
+		return
+	}
+
+	audit.Record(c, audit.Event{
+		Action:     "session.revoked_all",
+		TargetType: "user",
+		TargetID:   audit.ID(userID.(uint)),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out everywhere"})
+}
+
+// describeDevice turns a user agent into a short label such as "Chrome on macOS"
+func describeDevice(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+
+	browser := "Unknown browser"
+	for _, candidate := range []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+	} {
+		if strings.Contains(userAgent, candidate.token) {
+			browser = candidate.name
+			break
+		}
+	}
+
+	for _, candidate := range []struct{ token, name string }{
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Mac OS X", "macOS"},
+		{"Windows", "Windows"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	} {
+		if strings.Contains(userAgent, candidate.token) {
+			return browser + " on " + candidate.name
+		}
+	}
+	return browser
+}
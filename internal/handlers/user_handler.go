@@ -1,35 +1,68 @@
 package handlers
 
 import (
+	"errors"
+	"log"
+	"math"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/yourusername/ecommerce/configs"
+	"github.com/yourusername/ecommerce/internal/account"
+	"github.com/yourusername/ecommerce/internal/audit"
 	"github.com/yourusername/ecommerce/internal/auth"
 	"github.com/yourusername/ecommerce/internal/database"
+	"github.com/yourusername/ecommerce/internal/mailer"
+	"github.com/yourusername/ecommerce/internal/middleware"
 	"github.com/yourusername/ecommerce/internal/models"
+	"github.com/yourusername/ecommerce/internal/password"
+	"github.com/yourusername/ecommerce/internal/throttle"
 )
 
 // UserHandler handles user-related requests
 type UserHandler struct {
-	config *configs.Config
+	config         *configs.Config
+	mailer         mailer.Mailer
+	loginGuard     *throttle.LoginGuard
+	passwordPolicy *password.Validator
 }
 
 // NewUserHandler creates a new user handler
-func NewUserHandler(config *configs.Config) *UserHandler {
+func NewUserHandler(config *configs.Config, loginGuard *throttle.LoginGuard, passwordPolicy *password.Validator) *UserHandler {
 	return &UserHandler{
-		config: config,
+		config:         config,
+		mailer:         mailer.NewMailer(config),
+		loginGuard:     loginGuard,
+		passwordPolicy: passwordPolicy,
 	}
 }
 
 // Register handles user registration
 func (h *UserHandler) Register(c *gin.Context) {
-	var user models.User
-	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var registerData struct {
+		Email     string `json:"email" binding:"required,email"`
+		Password  string `json:"password" binding:"required"`
+		FirstName string `json:"first_name"`
+		LastName  string `json:"last_name"`
+	}
+
+	if !bindJSONWithFieldErrors(c, &registerData) {
 		return
 	}
 
+	if !checkPassword(c, h.passwordPolicy, "password", registerData.Password, registerData.Email) {
+		return
+	}
+
+	user := models.User{
+		Email:     registerData.Email,
+		FirstName: registerData.FirstName,
+		LastName:  registerData.LastName,
+	}
+
 	// Check if user already exists
 	var existingUser models.User
 	result := database.GetDB().Where("email = ?", user.Email).First(&existingUser)
@@ -40,29 +73,28 @@ func (h *UserHandler) Register(c *gin.Context) {
 
 	// Create user
 	user.Role = "user" // Default role
-	if err := database.GetDB().Create(&user).Error; err != nil {
+	user.EmailVerifiedAt = nil
+	if err := user.SetPassword(registerData.Password); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 		return
 	}
-
-	// Generate token
-	token, err := auth.GenerateToken(user.ID, user.Email, user.Role, h.config)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+	if err := database.GetDB().Create(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "User registered successfully",
-		"token":   token,
-		"user": gin.H{
-			"id":         user.ID,
-			"email":      user.Email,
-			"first_name": user.FirstName,
-			"last_name":  user.LastName,
-			"role":       user.Role,
-		},
+	audit.Record(c, audit.Event{
+		ActorID:    &user.ID,
+		Action:     "user.registered",
+		TargetType: "user",
+		TargetID:   audit.ID(user.ID),
 	})
+
+	if err := h.sendVerificationEmail(&user); err != nil {
+		log.Printf("Failed to create verification link: %v", err)
+	}
+
+	respondWithTokens(c, http.StatusCreated, "User registered successfully", &user, h.config)
 }
 
 // Login handles user login
@@ -77,58 +109,597 @@ func (h *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
+	// Refuse while the account or IP is backing off or locked out
+	if !checkLoginThrottle(c, h.loginGuard, loginData.Email) {
+		return
+	}
+
 	// Find user
 	var user models.User
-	result := database.GetDB().Where("email = ?", loginData.Email).First(&user)
+	result := database.GetDB().Where("email = ?", loginData.Email).Limit(1).Find(&user)
 	if result.RowsAffected == 0 {
+		// Spend the same time as a wrong password so unknown emails can't be told apart
+		models.CompareDummyPassword(loginData.Password)
+		recordLoginFailure(c, h.loginGuard, loginData.Email)
+		audit.Record(c, audit.Event{
+			Action:     "auth.login.failed",
+			TargetType: "email",
+			TargetID:   loginData.Email,
+			Diff:       gin.H{"reason": "unknown_email"},
+		})
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
 	// Check password
 	if err := user.ComparePassword(loginData.Password); err != nil {
+		recordLoginFailure(c, h.loginGuard, loginData.Email)
+		audit.Record(c, audit.Event{
+			Action:     "auth.login.failed",
+			TargetType: "user",
+			TargetID:   audit.ID(user.ID),
+			Diff:       gin.H{"reason": "wrong_password"},
+		})
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
-	// Generate token
-	token, err := auth.GenerateToken(user.ID, user.Email, user.Role, h.config)
+	if err := h.loginGuard.Succeed(loginData.Email); err != nil {
+		log.Printf("Failed to reset login failures: %v", err)
+	}
+
+	// Move the stored hash to the current scheme and cost while we have the password
+	if user.PasswordNeedsRehash() {
+		h.upgradePasswordHash(&user, loginData.Password)
+	}
+
+	completeLogin(c, &user, h.config)
+}
+
+// VerifyMFA completes a login for a user with two-factor authentication enabled
+func (h *UserHandler) VerifyMFA(c *gin.Context) {
+	var mfaData struct {
+		MFAToken     string `json:"mfa_token" binding:"required"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+
+	if err := c.ShouldBindJSON(&mfaData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The token is only marked used once the second factor checks out, so a
+	// mistyped code doesn't force the user to log in again
+	claims, err := auth.ValidatePurposeToken(mfaData.MFAToken, auth.PurposeMFAPending, h.config)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired MFA token"})
+		return
+	}
+	if used, err := auth.IsTokenRevoked(claims.ID); err != nil || used {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired MFA token"})
+		return
+	}
+
+	var user models.User
+	if err := database.GetDB().First(&user, claims.UserID).Error; err != nil || !user.MFAEnabled() {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired MFA token"})
+		return
+	}
+
+	// Wrong codes count towards the same lockout as wrong passwords
+	if !checkLoginThrottle(c, h.loginGuard, user.Email) {
+		return
+	}
+
+	if err := auth.VerifySecondFactor(&user, mfaData.Code, mfaData.RecoveryCode); err != nil {
+		if errors.Is(err, auth.ErrInvalidMFACode) {
+			recordLoginFailure(c, h.loginGuard, user.Email)
+			audit.Record(c, audit.Event{
+				Action:     "auth.login.failed",
+				TargetType: "user",
+				TargetID:   audit.ID(user.ID),
+				Diff:       gin.H{"reason": "invalid_mfa_code"},
+			})
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid two-factor code"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify two-factor code"})
+		return
+	}
+
+	// Each pending login can be completed once
+	if _, err := auth.ConsumePurposeToken(mfaData.MFAToken, auth.PurposeMFAPending, h.config); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired MFA token"})
+		return
+	}
+
+	respondWithTokens(c, http.StatusOK, "Login successful", &user, h.config)
+}
+
+// checkLoginThrottle writes a 429 response and returns false if logins for the
+// email or from the client IP are currently blocked
+func checkLoginThrottle(c *gin.Context, guard *throttle.LoginGuard, email string) bool {
+	wait, err := guard.Wait(email, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check login attempts"})
+		return false
+	}
+
+	if wait > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed login attempts, please try again later"})
+		return false
+	}
+
+	return true
+}
+
+// recordLoginFailure counts a failed login against the email and client IP
+func recordLoginFailure(c *gin.Context, guard *throttle.LoginGuard, email string) {
+	if err := guard.Fail(email, c.ClientIP()); err != nil {
+		log.Printf("Failed to record login failure: %v", err)
+	}
+}
+
+// Refresh exchanges a refresh token for a new access and refresh token
+func (h *UserHandler) Refresh(c *gin.Context) {
+	var refreshData struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+
+	// Browser clients in cookie mode send the refresh token as a cookie
+	if cookie, err := c.Cookie(middleware.RefreshTokenCookie); h.config.CookieAuth && err == nil && cookie != "" {
+		refreshData.RefreshToken = cookie
+	} else if err := c.ShouldBindJSON(&refreshData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := auth.RotateRefreshToken(refreshData.RefreshToken, clientInfo(c), h.config)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrRefreshTokenReused):
+			audit.Record(c, audit.Event{Action: "auth.token.reuse_detected"})
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token has already been used; the session has been revoked"})
+		case errors.Is(err, auth.ErrInvalidRefreshToken):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		}
+		return
+	}
+
+	audit.Record(c, audit.Event{
+		ActorID:    &tokens.UserID,
+		Action:     "auth.token.refreshed",
+		TargetType: "user",
+		TargetID:   audit.ID(tokens.UserID),
+	})
+
+	response := gin.H{"expires_in": tokens.ExpiresIn}
+	if !writeTokens(c, response, tokens, h.config) {
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Logout revokes the current access token and its refresh token family
+func (h *UserHandler) Logout(c *gin.Context) {
+	tokenID := c.GetString("tokenID")
+	expiresAt := c.GetTime("tokenExpiresAt")
+
+	if err := auth.RevokeSessionByAccessToken(tokenID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+
+	if err := auth.RevokeAccessToken(tokenID, expiresAt); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke token"})
+		return
+	}
+
+	if h.config.CookieAuth {
+		clearSessionCookies(c, h.config)
+	}
+
+	audit.Record(c, audit.Event{Action: "auth.logout"})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// ForgotPassword emails a password reset link. The response is the same
+// whether or not the email belongs to an account.
+func (h *UserHandler) ForgotPassword(c *gin.Context) {
+	var forgotData struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+
+	if err := c.ShouldBindJSON(&forgotData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	result := database.GetDB().Where("email = ?", forgotData.Email).Limit(1).Find(&user)
+	if result.RowsAffected > 0 {
+		token, err := auth.CreatePasswordResetToken(user.ID, h.config)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reset token"})
+			return
+		}
+
+		// Send in the background so response timing doesn't reveal the account
+		link := h.config.AppBaseURL + "/reset-password?token=" + url.QueryEscape(token)
+		go func(email string) {
+			err := h.mailer.Send(mailer.Message{
+				To:      email,
+				Subject: "Reset your password",
+				Body: "We received a request to reset your password.\n\n" +
+					"Use the link below to choose a new one. It expires in " + h.config.PasswordResetTTL.String() + ".\n\n" +
+					link + "\n\n" +
+					"If you didn't request this, you can ignore this email.",
+			})
+			if err != nil {
+				log.Printf("Failed to send password reset email: %v", err)
+			}
+		}(user.Email)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "If an account exists for this email, a reset link has been sent"})
+}
+
+// ResetPassword sets a new password using a reset token and revokes existing sessions
+func (h *UserHandler) ResetPassword(c *gin.Context) {
+	var resetData struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+
+	if !bindJSONWithFieldErrors(c, &resetData) {
+		return
+	}
+
+	resetUser, err := auth.PasswordResetTokenUser(resetData.Token)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidResetToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	if !checkPassword(c, h.passwordPolicy, "password", resetData.Password, resetUser.Email) {
+		return
+	}
+
+	user, err := auth.ResetPassword(resetData.Token, resetData.Password)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidResetToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	audit.Record(c, audit.Event{
+		ActorID:    &user.ID,
+		Action:     "auth.password.reset",
+		TargetType: "user",
+		TargetID:   audit.ID(user.ID),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset"})
+}
+
+// VerifyEmail marks the user's email as verified using the link sent on registration
+func (h *UserHandler) VerifyEmail(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Verification token is required"})
+		return
+	}
+
+	claims, err := auth.ValidatePurposeToken(token, auth.PurposeEmailVerification, h.config)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification link"})
+		return
+	}
+
+	// The link is only valid for the address it was sent to
+	result := database.GetDB().Model(&models.User{}).
+		Where("id = ? AND email = ? AND email_verified_at IS NULL", claims.UserID, claims.Email).
+		Update("email_verified_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		var user models.User
+		if err := database.GetDB().Where("id = ? AND email = ?", claims.UserID, claims.Email).First(&user).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification link"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
+}
+
+// ResendVerification sends a new verification link to the current user
+func (h *UserHandler) ResendVerification(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var user models.User
+	if err := database.GetDB().First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if user.EmailVerifiedAt != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email is already verified"})
+		return
+	}
+
+	if err := h.sendVerificationEmail(&user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create verification link"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+}
+
+// sendVerificationEmail emails a signed verification link in the background
+func (h *UserHandler) sendVerificationEmail(user *models.User) error {
+	token, err := auth.GeneratePurposeToken(auth.PurposeEmailVerification, user.ID, user.Email, h.config.EmailVerificationTTL, h.config)
+	if err != nil {
+		return err
+	}
+
+	link := h.config.AppBaseURL + "/api/auth/verify?token=" + url.QueryEscape(token)
+	go func(email string) {
+		err := h.mailer.Send(mailer.Message{
+			To:      email,
+			Subject: "Verify your email address",
+			Body: "Please confirm your email address by opening the link below. It expires in " + h.config.EmailVerificationTTL.String() + ".\n\n" +
+				link,
+		})
+		if err != nil {
+			log.Printf("Failed to send verification email: %v", err)
+		}
+	}(user.Email)
+
+	return nil
+}
+
+// GetProfile returns the user's profile
+func (h *UserHandler) GetProfile(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var user models.User
+	if err := database.GetDB().First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Login successful",
-		"token":   token,
 		"user": gin.H{
-			"id":         user.ID,
-			"email":      user.Email,
-			"first_name": user.FirstName,
-			"last_name":  user.LastName,
-			"role":       user.Role,
+			"id":                user.ID,
+			"email":             user.Email,
+			"first_name":        user.FirstName,
+			"last_name":         user.LastName,
+			"role":              user.Role,
+			"email_verified_at": user.EmailVerifiedAt,
+			"mfa_enabled":       user.MFAEnabled(),
+			"created_at":        user.CreatedAt,
 		},
 	})
 }
 
-// GetProfile returns the user's profile
-func (h *UserHandler) GetProfile(c *gin.Context) {
+// UpdateProfile changes the user's name and email. A new email has to be verified again.
+func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	userID, _ := c.Get("userID")
 
+	var profileData struct {
+		Email     *string `json:"email" binding:"omitempty,email"`
+		FirstName *string `json:"first_name"`
+		LastName  *string `json:"last_name"`
+	}
+
+	if err := c.ShouldBindJSON(&profileData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	var user models.User
 	if err := database.GetDB().First(&user, userID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
+	updates := map[string]interface{}{}
+	if profileData.FirstName != nil {
+		updates["first_name"] = *profileData.FirstName
+	}
+	if profileData.LastName != nil {
+		updates["last_name"] = *profileData.LastName
+	}
+
+	emailChanged := profileData.Email != nil && *profileData.Email != user.Email
+	if emailChanged {
+		var count int64
+		database.GetDB().Model(&models.User{}).Where("email = ? AND id <> ?", *profileData.Email, user.ID).Count(&count)
+		if count > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "User with this email already exists"})
+			return
+		}
+		updates["email"] = *profileData.Email
+		updates["email_verified_at"] = nil
+	}
+
+	if len(updates) > 0 {
+		if err := database.GetDB().Model(&models.User{}).Where("id = ?", user.ID).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
+			return
+		}
+	}
+
+	before := user
+	database.GetDB().First(&user, user.ID)
+
+	audit.Record(c, audit.Event{
+		Action:     "user.profile.updated",
+		TargetType: "user",
+		TargetID:   audit.ID(user.ID),
+		Diff:       audit.Changes(before, user),
+	})
+
+	if emailChanged {
+		if err := h.sendVerificationEmail(&user); err != nil {
+			log.Printf("Failed to create verification link: %v", err)
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
+		"message": "Profile updated successfully",
 		"user": gin.H{
-			"id":         user.ID,
-			"email":      user.Email,
-			"first_name": user.FirstName,
-			"last_name":  user.LastName,
-			"role":       user.Role,
-			"created_at": user.CreatedAt,
+			"id":                user.ID,
+			"email":             user.Email,
+			"first_name":        user.FirstName,
+			"last_name":         user.LastName,
+			"role":              user.Role,
+			"email_verified_at": user.EmailVerifiedAt,
+			"mfa_enabled":       user.MFAEnabled(),
+			"created_at":        user.CreatedAt,
 		},
 	})
-}
\ No newline at end of file
+}
+
+// ChangePassword sets a new password after checking the current one. Every
+// other session is revoked; the one making the request stays logged in.
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var passwordData struct {
+		CurrentPassword string `json:"current_password" binding:"required"`
+		NewPassword     string `json:"new_password" binding:"required"`
+	}
+
+	if !bindJSONWithFieldErrors(c, &passwordData) {
+		return
+	}
+
+	var user models.User
+	if err := database.GetDB().First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if !h.checkCurrentPassword(c, &user, passwordData.CurrentPassword) {
+		return
+	}
+
+	if !checkPassword(c, h.passwordPolicy, "new_password", passwordData.NewPassword, user.Email) {
+		return
+	}
+
+	if err := user.SetPassword(passwordData.NewPassword); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		return
+	}
+	if err := database.GetDB().Save(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		return
+	}
+
+	if err := auth.RevokeOtherSessions(user.ID, c.GetString("tokenID")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Password changed but failed to revoke other sessions"})
+		return
+	}
+
+	audit.Record(c, audit.Event{
+		Action:     "auth.password.changed",
+		TargetType: "user",
+		TargetID:   audit.ID(user.ID),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully"})
+}
+
+// DeleteAccount deletes the user's account. Personal data is anonymized and
+// order history is kept for accounting.
+func (h *UserHandler) DeleteAccount(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var deleteData struct {
+		Password string `json:"password" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&deleteData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := database.GetDB().First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if !h.checkCurrentPassword(c, &user, deleteData.Password) {
+		return
+	}
+
+	if !checkNotLastAdmin(c, &user) {
+		return
+	}
+
+	if err := account.Anonymize(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
+		return
+	}
+
+	audit.Record(c, audit.Event{
+		Action:     "user.deleted",
+		TargetType: "user",
+		TargetID:   audit.ID(user.ID),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account deleted successfully"})
+}
+
+// checkCurrentPassword writes an error response and returns false unless the
+// password is the user's. Wrong guesses count towards the login lockout.
+func (h *UserHandler) checkCurrentPassword(c *gin.Context, user *models.User, password string) bool {
+	if !checkLoginThrottle(c, h.loginGuard, user.Email) {
+		return false
+	}
+
+	if err := user.ComparePassword(password); err != nil {
+		recordLoginFailure(c, h.loginGuard, user.Email)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
+		return false
+	}
+
+	return true
+}
+
+// upgradePasswordHash re-hashes a user's password with the current scheme.
+// Failing to upgrade doesn't fail the login; it is retried next time.
+func (h *UserHandler) upgradePasswordHash(user *models.User, plain string) {
+	hashedPassword, err := password.Hash(plain)
+	if err != nil {
+		log.Printf("Failed to rehash password for user %d: %v", user.ID, err)
+		return
+	}
+
+	if err := database.GetDB().Model(&models.User{}).Where("id = ? AND password = ?", user.ID, user.Password).
+		Update("password", hashedPassword).Error; err != nil {
+		log.Printf("Failed to store rehashed password for user %d: %v", user.ID, err)
+		return
+	}
+	user.Password = hashedPassword
+}
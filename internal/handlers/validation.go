@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/yourusername/ecommerce/internal/password"
+)
+
+// FieldError describes why one request field was rejected
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func init() {
+	// Report fields by their JSON names rather than Go struct field names
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+	}
+}
+
+// respondFieldErrors writes a 400 response listing the rejected fields
+func respondFieldErrors(c *gin.Context, fields []FieldError) {
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":  "Validation failed",
+		"fields": fields,
+	})
+}
+
+// bindJSONWithFieldErrors binds the request body, writing a 400 response with
+// field errors and returning false if it doesn't validate
+func bindJSONWithFieldErrors(c *gin.Context, obj interface{}) bool {
+	err := c.ShouldBindJSON(obj)
+	if err == nil {
+		return true
+	}
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	fields := make([]FieldError, 0, len(validationErrors))
+	for _, fieldError := range validationErrors {
+		fields = append(fields, FieldError{
+			Field:   fieldError.Field(),
+			Code:    fieldError.Tag(),
+			Message: fieldErrorMessage(fieldError),
+		})
+	}
+	respondFieldErrors(c, fields)
+	return false
+}
+
+// checkPassword writes a 400 response and returns false if a new password
+// breaks the password policy
+func checkPassword(c *gin.Context, policy *password.Validator, field, newPassword, email string) bool {
+	violations := policy.Validate(newPassword, email)
+	if len(violations) == 0 {
+		return true
+	}
+
+	fields := make([]FieldError, 0, len(violations))
+	for _, violation := range violations {
+		fields = append(fields, FieldError{
+			Field:   field,
+			Code:    violation.Code,
+			Message: violation.Message,
+		})
+	}
+	respondFieldErrors(c, fields)
+	return false
+}
+
+func fieldErrorMessage(fieldError validator.FieldError) string {
+	switch fieldError.Tag() {
+	case "required":
+		return "This field is required"
+	case "email":
+		return "Must be a valid email address"
+	case "min":
+		return "Must be at least " + fieldError.Param() + " characters"
+	case "max":
+		return "Must be at most " + fieldError.Param() + " characters"
+	default:
+		return "Is invalid"
+	}
+}
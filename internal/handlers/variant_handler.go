@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/ecommerce/internal/audit"
+	"github.com/yourusername/ecommerce/internal/catalog"
+	"github.com/yourusername/ecommerce/internal/database"
+	"github.com/yourusername/ecommerce/internal/models"
+	"gorm.io/gorm"
+)
+
+// VariantHandler handles product option and variant requests
+type VariantHandler struct{}
+
+// NewVariantHandler creates a new variant handler
+func NewVariantHandler() *VariantHandler {
+	return &VariantHandler{}
+}
+
+// preloadVariants loads a product's options and variants in display order
+func preloadVariants(query *gorm.DB) *gorm.DB {
+	return query.
+		Preload("Options", func(db *gorm.DB) *gorm.DB { return db.Order("position, id") }).
+		Preload("Options.Values", func(db *gorm.DB) *gorm.DB { return db.Order("position, id") }).
+		Preload("Variants", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Variants.Options").
+		Preload("Variants.Images")
+}
+
+// GetVariants returns a product's options and variants
+func (h *VariantHandler) GetVariants(c *gin.Context) {
+	var product models.Product
+	if err := preloadVariants(database.GetDB()).First(&product, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"options": product.Options, "variants": product.Variants})
+}
+
+// GenerateVariants replaces a product's options and builds a variant for
+// every combination of their values
+func (h *VariantHandler) GenerateVariants(c *gin.Context) {
+	var product models.Product
+	if err := preloadVariants(database.GetDB()).First(&product, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+	before := gin.H{"options": product.Options, "variants": product.Variants}
+
+	var generateData struct {
+		Options   []catalog.Option `json:"options"`
+		Price     *float64         `json:"price" binding:"omitempty,min=0"`
+		Stock     int              `json:"stock" binding:"min=0"`
+		SKUPrefix string           `json:"sku_prefix"`
+	}
+
+	if err := c.ShouldBindJSON(&generateData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		return catalog.GenerateVariants(tx, product.ID, generateData.Options, catalog.VariantDefaults{
+			Price:     generateData.Price,
+			Stock:     generateData.Stock,
+			SKUPrefix: generateData.SKUPrefix,
+		})
+	})
+	switch {
+	case errors.Is(err, catalog.ErrInvalidOptions):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Each option needs a unique name and at least one value, and values must be unique"})
+		return
+	case errors.Is(err, catalog.ErrTooManyVariants):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Options may generate at most " + strconv.Itoa(catalog.MaxVariants) + " variants"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate variants"})
+		return
+	}
+
+	product = models.Product{}
+	preloadVariants(database.GetDB()).First(&product, c.Param("id"))
+
+	audit.Record(c, audit.Event{
+		Action:     "product.variants_generated",
+		TargetType: "product",
+		TargetID:   audit.ID(product.ID),
+		Diff:       audit.Changes(before, gin.H{"options": product.Options, "variants": product.Variants}),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"options": product.Options, "variants": product.Variants})
+}
+
+// UpdateVariant sets a variant's SKU, price override, stock and images
+func (h *VariantHandler) UpdateVariant(c *gin.Context) {
+	var variant models.Variant
+	if err := database.GetDB().Preload("Images").
+		Where("id = ? AND product_id = ?", c.Param("variantId"), c.Param("id")).
+		First(&variant).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Variant not found"})
+		return
+	}
+	before := variant
+
+	var variantData struct {
+		SKU    string   `json:"sku" binding:"required"`
+		Price  *float64 `json:"price" binding:"omitempty,min=0"`
+		Stock  int      `json:"stock" binding:"min=0"`
+		Images []string `json:"images"`
+	}
+
+	if err := c.ShouldBindJSON(&variantData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	variant.SKU = variantData.SKU
+	variant.Price = variantData.Price
+	variant.Stock = variantData.Stock
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := catalog.UpdateVariant(tx, &variant); err != nil {
+			return err
+		}
+		if variantData.Images == nil {
+			return nil
+		}
+
+		if err := tx.Where("variant_id = ?", variant.ID).Delete(&models.Image{}).Error; err != nil {
+			return err
+		}
+		variant.Images = []models.Image{}
+		for _, url := range variantData.Images {
+			image := models.Image{URL: url, ProductID: variant.ProductID, VariantID: &variant.ID}
+			if err := tx.Create(&image).Error; err != nil {
+				return err
+			}
+			variant.Images = append(variant.Images, image)
+		}
+		return nil
+	})
+	if errors.Is(err, catalog.ErrSKUTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": "SKU is already in use"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update variant"})
+		return
+	}
+
+	audit.Record(c, audit.Event{
+		Action:     "variant.updated",
+		TargetType: "variant",
+		TargetID:   audit.ID(variant.ID),
+		Diff:       audit.Changes(before, variant),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"variant": variant})
+}
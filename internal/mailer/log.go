@@ -0,0 +1,48 @@
+package mailer
+
+import (
+	"log"
+	"os"
+	"sync"
+
+	"github.com/yourusername/ecommerce/configs"
+)
+
+// LogMailer writes messages to a file, or to the standard logger when no
+// file is configured, instead of delivering them. Meant for development and tests.
+type LogMailer struct {
+	mu   sync.Mutex
+	from string
+	path string
+}
+
+// NewLogMailer creates a new log mailer
+func NewLogMailer(config *configs.Config) *LogMailer {
+	return &LogMailer{
+		from: config.MailFrom,
+		path: config.MailLogPath,
+	}
+}
+
+// Send records a message
+func (m *LogMailer) Send(msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rendered := buildMessage(m.from, msg)
+	if m.path == "" {
+		log.Printf("Mail to %s:\n%s", msg.To, rendered)
+		return nil
+	}
+
+	f, err := os.OpenFile(m.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(rendered, "\r\n\r\n"...)); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package mailer
+
+import (
+	"github.com/yourusername/ecommerce/configs"
+)
+
+// Message represents an email to be delivered
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers email messages
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// NewMailer creates the mailer selected by the MAIL_DRIVER setting
+func NewMailer(config *configs.Config) Mailer {
+	switch config.MailDriver {
+	case "smtp":
+		return NewSMTPMailer(config)
+	default:
+		return NewLogMailer(config)
+	}
+}
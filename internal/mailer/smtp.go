@@ -0,0 +1,47 @@
+package mailer
+
+import (
+	"fmt"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/yourusername/ecommerce/configs"
+)
+
+// SMTPMailer sends email through an SMTP server
+type SMTPMailer struct {
+	config *configs.Config
+}
+
+// NewSMTPMailer creates a new SMTP mailer
+func NewSMTPMailer(config *configs.Config) *SMTPMailer {
+	return &SMTPMailer{
+		config: config,
+	}
+}
+
+// Send delivers a plain text message
+func (m *SMTPMailer) Send(msg Message) error {
+	var auth smtp.Auth
+	if m.config.SMTPUsername != "" {
+		auth = smtp.PlainAuth("", m.config.SMTPUsername, m.config.SMTPPassword, m.config.SMTPHost)
+	}
+
+	addr := m.config.SMTPHost + ":" + m.config.SMTPPort
+	return smtp.SendMail(addr, auth, m.config.MailFrom, []string{msg.To}, buildMessage(m.config.MailFrom, msg))
+}
+
+// buildMessage renders the headers and body of a message
+func buildMessage(from string, msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
@@ -1,18 +1,29 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/yourusername/ecommerce/configs"
+	"github.com/yourusername/ecommerce/internal/audit"
 	"github.com/yourusername/ecommerce/internal/auth"
+	"github.com/yourusername/ecommerce/internal/database"
+	"github.com/yourusername/ecommerce/internal/models"
 )
 
 // AuthMiddleware checks if the user is authenticated
 func AuthMiddleware(config *configs.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" && config.CookieAuth {
+			// Browser clients send the access token in a cookie instead
+			if token, err := c.Cookie(AccessTokenCookie); err == nil && token != "" {
+				authHeader = "Bearer " + token
+			}
+		}
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
 			c.Abort()
@@ -20,12 +31,17 @@ func AuthMiddleware(config *configs.Config) gin.HandlerFunc {
 		}
 
 		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header format must be Bearer {token}"})
+		if len(parts) != 2 || (parts[0] != "Bearer" && parts[0] != "ApiKey") {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header format must be Bearer {token} or ApiKey {key}"})
 			c.Abort()
 			return
 		}
 
+		if parts[0] == "ApiKey" {
+			authenticateAPIKey(c, parts[1])
+			return
+		}
+
 		claims, err := auth.ValidateToken(parts[1], config)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
@@ -33,24 +49,165 @@ func AuthMiddleware(config *configs.Config) gin.HandlerFunc {
 			return
 		}
 
+		// Check the revocation list
+		revoked, err := auth.IsTokenRevoked(claims.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify token"})
+			c.Abort()
+			return
+		}
+		if revoked {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+			c.Abort()
+			return
+		}
+
+		// Disabled accounts lose access straight away rather than when the token expires
+		if !checkAccountEnabled(c, claims.UserID) {
+			return
+		}
+
 		// Set user information in the context
 		c.Set("userID", claims.UserID)
 		c.Set("email", claims.Email)
 		c.Set("role", claims.Role)
+		c.Set("permissions", claims.Permissions)
+		c.Set("tokenID", claims.ID)
+		c.Set("tokenExpiresAt", claims.ExpiresAt.Time)
+
+		// Last activity is written in the background so requests don't wait on it
+		if claims.SessionID != "" {
+			c.Set("sessionID", claims.SessionID)
+			auth.TouchSession(claims.SessionID, c.ClientIP())
+		}
+
+		// Impersonation: expose the admin really making the request and log every request they make
+		if claims.Actor != nil {
+			if !checkAccountEnabled(c, claims.Actor.UserID) {
+				return
+			}
+			c.Set("actorID", claims.Actor.UserID)
+			c.Set("actorEmail", claims.Actor.Email)
+
+			c.Next()
+
+			audit.Record(c, audit.Event{
+				Action:     "impersonation.request",
+				TargetType: "route",
+				TargetID:   c.Request.Method + " " + c.FullPath(),
+				Diff:       gin.H{"status": c.Writer.Status()},
+			})
+			return
+		}
 
 		c.Next()
 	}
 }
 
-// AdminMiddleware checks if the user is an admin
-func AdminMiddleware() gin.HandlerFunc {
+// DenyImpersonation blocks routes an impersonating admin must not use, such
+// as changing the customer's password or paying. It must run after AuthMiddleware.
+func DenyImpersonation() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		role, exists := c.Get("role")
-		if !exists || role != "admin" {
+		if _, impersonating := c.Get("actorID"); impersonating {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed while impersonating a user"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// checkAccountEnabled aborts the request unless the user exists and is not disabled
+func checkAccountEnabled(c *gin.Context, userID uint) bool {
+	var user models.User
+	result := database.GetDB().Select("id", "disabled").Where("id = ?", userID).Limit(1).Find(&user)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify token"})
+		c.Abort()
+		return false
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+		c.Abort()
+		return false
+	}
+	if user.Disabled {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is disabled"})
+		c.Abort()
+		return false
+	}
+	return true
+}
+
+// authenticateAPIKey sets the same user information as a bearer token would,
+// plus the key's ID, or aborts the request
+func authenticateAPIKey(c *gin.Context, rawKey string) {
+	principal, err := auth.AuthenticateAPIKey(rawKey)
+	if errors.Is(err, auth.ErrInvalidAPIKey) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid, expired or revoked API key"})
+		c.Abort()
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify API key"})
+		c.Abort()
+		return
+	}
+
+	c.Set("userID", principal.User.ID)
+	c.Set("email", principal.User.Email)
+	c.Set("role", principal.User.Role)
+	c.Set("permissions", principal.Permissions)
+	c.Set("apiKeyID", principal.Key.ID)
+
+	c.Next()
+}
+
+// RequirePermission checks that the user's role grants every listed permission
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granted := c.GetStringSlice("permissions")
+		for _, permission := range permissions {
+			if !slices.Contains(granted, permission) {
+				audit.Record(c, audit.Event{
+					Action:     "permission.denied",
+					TargetType: "route",
+					TargetID:   c.Request.Method + " " + c.FullPath(),
+					Diff:       gin.H{"permission": permission},
+				})
+				c.JSON(http.StatusForbidden, gin.H{"error": "Permission required: " + permission})
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// AdminMFAMiddleware blocks privileged routes until the user has enabled
+// two-factor authentication. It must run after AuthMiddleware.
+func AdminMFAMiddleware(config *configs.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !config.RequireAdminMFA {
+			c.Next()
+			return
+		}
+
+		userID, _ := c.Get("userID")
+
+		var user models.User
+		if err := database.GetDB().Select("id", "totp_enabled_at").First(&user, userID).Error; err != nil {
 			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
 			c.Abort()
 			return
 		}
+
+		if !user.MFAEnabled() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Two-factor authentication must be enabled to use admin routes"})
+			c.Abort()
+			return
+		}
+
 		c.Next()
 	}
-}
\ No newline at end of file
+}
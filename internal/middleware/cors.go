@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/ecommerce/configs"
+)
+
+// CORSPolicy decides which cross-origin requests browsers may make
+type CORSPolicy struct {
+	// AllowedOrigins holds exact origins such as https://shop.example.com,
+	// wildcard subdomains such as https://*.example.com, or * for any origin
+	AllowedOrigins   []string
+	AllowCredentials bool
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	MaxAge           time.Duration // how long browsers may cache a preflight response
+}
+
+// CORSPolicyFromConfig builds the default policy from the CORS_* settings
+func CORSPolicyFromConfig(config *configs.Config) CORSPolicy {
+	return CORSPolicy{
+		AllowedOrigins:   config.CORSAllowedOrigins,
+		AllowCredentials: config.CORSAllowCredentials,
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   config.CORSAllowedHeaders,
+		ExposedHeaders:   config.CORSExposedHeaders,
+		MaxAge:           config.CORSMaxAge,
+	}
+}
+
+// allowedOrigin returns the Access-Control-Allow-Origin value for a request
+// origin, or "" if the origin is not allowed. Origins listed explicitly are
+// echoed back; anything else admitted by a * entry gets *.
+func (p CORSPolicy) allowedOrigin(origin string) string {
+	if p.listsOrigin(origin) {
+		return origin
+	}
+	if slices.Contains(p.AllowedOrigins, "*") {
+		return "*"
+	}
+	return ""
+}
+
+// listsOrigin reports whether an origin matches an exact or wildcard
+// subdomain entry. Only these origins are allowed credentials.
+func (p CORSPolicy) listsOrigin(origin string) bool {
+	for _, allowed := range p.AllowedOrigins {
+		if strings.EqualFold(allowed, origin) || matchesWildcardOrigin(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesWildcardOrigin matches an origin against a pattern such as
+// https://*.example.com, which allows any subdomain but not the bare domain
+func matchesWildcardOrigin(pattern, origin string) bool {
+	scheme, hostPattern, ok := strings.Cut(pattern, "://")
+	if !ok || !strings.HasPrefix(hostPattern, "*.") {
+		return false
+	}
+	originScheme, host, ok := strings.Cut(strings.ToLower(origin), "://")
+	if !ok || originScheme != strings.ToLower(scheme) || strings.Contains(host, "/") {
+		return false
+	}
+	suffix := strings.ToLower(hostPattern[1:])
+	return strings.HasSuffix(host, suffix) && len(host) > len(suffix)
+}
+
+// CORS applies a default policy, overridden for requests under a path prefix
+// by Override. Overrides are matched by path rather than attached to route
+// groups because preflight requests don't match any route.
+type CORS struct {
+	defaultPolicy CORSPolicy
+	overrides     []corsOverride
+}
+
+type corsOverride struct {
+	prefix string
+	policy CORSPolicy
+}
+
+// NewCORS creates a CORS handler with a default policy
+func NewCORS(policy CORSPolicy) *CORS {
+	return &CORS{defaultPolicy: policy}
+}
+
+// Override uses a different policy for requests whose path starts with
+// prefix. The longest matching prefix wins.
+func (cors *CORS) Override(prefix string, policy CORSPolicy) *CORS {
+	cors.overrides = append(cors.overrides, corsOverride{prefix: prefix, policy: policy})
+	slices.SortStableFunc(cors.overrides, func(a, b corsOverride) int {
+		return len(b.prefix) - len(a.prefix)
+	})
+	return cors
+}
+
+// policyFor returns the policy that applies to a request path
+func (cors *CORS) policyFor(path string) CORSPolicy {
+	for _, override := range cors.overrides {
+		if path == override.prefix || strings.HasPrefix(path, strings.TrimSuffix(override.prefix, "/")+"/") {
+			return override.policy
+		}
+	}
+	return cors.defaultPolicy
+}
+
+// Handler returns the middleware. It must be registered on the router
+// itself so it also sees preflight requests.
+func (cors *CORS) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		policy := cors.policyFor(c.Request.URL.Path)
+		header := c.Writer.Header()
+		origin := c.GetHeader("Origin")
+
+		// The response depends on the Origin header unless every origin
+		// gets the same wildcard answer
+		if !slices.Equal(policy.AllowedOrigins, []string{"*"}) {
+			header.Add("Vary", "Origin")
+		}
+
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		if preflight {
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		allowOrigin := ""
+		if origin != "" {
+			allowOrigin = policy.allowedOrigin(origin)
+		}
+
+		if allowOrigin != "" {
+			header.Set("Access-Control-Allow-Origin", allowOrigin)
+			if policy.AllowCredentials && allowOrigin != "*" {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+			if !preflight && len(policy.ExposedHeaders) > 0 {
+				header.Set("Access-Control-Expose-Headers", strings.Join(policy.ExposedHeaders, ", "))
+			}
+		}
+
+		if !preflight {
+			c.Next()
+			return
+		}
+
+		// Preflight requests are answered here; a disallowed origin simply
+		// gets no CORS headers, which the browser treats as a refusal
+		if allowOrigin != "" {
+			header.Set("Access-Control-Allow-Methods", strings.Join(policy.AllowedMethods, ", "))
+			header.Set("Access-Control-Allow-Headers", strings.Join(policy.AllowedHeaders, ", "))
+			if policy.MaxAge > 0 {
+				header.Set("Access-Control-Max-Age", strconv.Itoa(int(policy.MaxAge.Seconds())))
+			}
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
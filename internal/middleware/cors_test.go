@@ -0,0 +1,204 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// corsRequest sends a request with an Origin header through a router using
+// the CORS handler and returns the response
+func corsRequest(cors *CORS, method, path, origin string, preflight bool) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.Use(cors.Handler())
+	router.Any("/*path", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(method, path, nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	if preflight {
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		req.Header.Set("Access-Control-Request-Headers", "Content-Type")
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCORSAllowedOrigin(t *testing.T) {
+	listed := CORSPolicy{
+		AllowedOrigins:   []string{"https://shop.example.com", "https://*.example.org", "http://localhost:3000"},
+		AllowCredentials: true,
+	}
+	anyOrigin := CORSPolicy{AllowedOrigins: []string{"*"}, AllowCredentials: true}
+	mixed := CORSPolicy{AllowedOrigins: []string{"https://shop.example.com", "*"}, AllowCredentials: true}
+
+	tests := []struct {
+		name            string
+		policy          CORSPolicy
+		origin          string
+		wantOrigin      string
+		wantCredentials bool
+	}{
+		{"exact match", listed, "https://shop.example.com", "https://shop.example.com", true},
+		{"exact match ignores case", listed, "https://SHOP.example.com", "https://SHOP.example.com", true},
+		{"unlisted origin", listed, "https://evil.example.com", "", false},
+		{"listed origin as a prefix", listed, "https://shop.example.com.attacker.net", "", false},
+		{"scheme mismatch", listed, "http://shop.example.com", "", false},
+		{"explicit default port", listed, "https://shop.example.com:443", "", false},
+		{"matching port", listed, "http://localhost:3000", "http://localhost:3000", true},
+		{"other port", listed, "http://localhost:3001", "", false},
+		{"missing port", listed, "http://localhost", "", false},
+		{"wildcard subdomain", listed, "https://api.example.org", "https://api.example.org", true},
+		{"wildcard nested subdomain", listed, "https://a.b.example.org", "https://a.b.example.org", true},
+		{"wildcard excludes bare domain", listed, "https://example.org", "", false},
+		{"wildcard excludes empty label", listed, "https://.example.org", "", false},
+		{"wildcard suffix in a longer domain", listed, "https://evil.example.org.attacker.net", "", false},
+		{"wildcard suffix without a dot", listed, "https://evilexample.org", "", false},
+		{"wildcard scheme mismatch", listed, "http://api.example.org", "", false},
+		{"wildcard with a port", listed, "https://api.example.org:8443", "", false},
+		{"wildcard with a path", listed, "https://api.example.org/x", "", false},
+		{"any origin without credentials", anyOrigin, "https://evil.example.com", "*", false},
+		{"null origin with any", anyOrigin, "null", "*", false},
+		{"listed origin alongside any keeps credentials", mixed, "https://shop.example.com", "https://shop.example.com", true},
+		{"unlisted origin alongside any gets no credentials", mixed, "https://evil.example.com", "*", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := corsRequest(NewCORS(tt.policy), http.MethodGet, "/api/products", tt.origin, false)
+
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Credentials") == "true"; got != tt.wantCredentials {
+				t.Errorf("Access-Control-Allow-Credentials = %v, want %v", got, tt.wantCredentials)
+			}
+			if w.Code != http.StatusOK {
+				t.Errorf("status = %d; a simple request must reach the handler", w.Code)
+			}
+		})
+	}
+}
+
+func TestCORSVaryOrigin(t *testing.T) {
+	tests := []struct {
+		name     string
+		origins  []string
+		origin   string
+		wantVary bool
+	}{
+		{"listed origin", []string{"https://shop.example.com"}, "https://shop.example.com", true},
+		// A cached refusal must not be served to an allowed origin
+		{"refused origin", []string{"https://shop.example.com"}, "https://evil.example.com", true},
+		{"no origin", []string{"https://shop.example.com"}, "", true},
+		{"any origin", []string{"*"}, "https://evil.example.com", false},
+		{"listed origin alongside any", []string{"https://shop.example.com", "*"}, "https://evil.example.com", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := corsRequest(NewCORS(CORSPolicy{AllowedOrigins: tt.origins}), http.MethodGet, "/api/products", tt.origin, false)
+
+			vary := w.Header().Values("Vary")
+			if got := slices.Contains(vary, "Origin"); got != tt.wantVary {
+				t.Errorf("Vary = %v, want Origin included: %v", vary, tt.wantVary)
+			}
+		})
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	policy := CORSPolicy{
+		AllowedOrigins:   []string{"https://shop.example.com"},
+		AllowCredentials: true,
+		AllowedMethods:   []string{"GET", "POST"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		ExposedHeaders:   []string{"X-Request-ID"},
+		MaxAge:           10 * time.Minute,
+	}
+
+	t.Run("allowed", func(t *testing.T) {
+		w := corsRequest(NewCORS(policy), http.MethodOptions, "/api/orders", "https://shop.example.com", true)
+
+		if w.Code != http.StatusNoContent {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusNoContent)
+		}
+		for name, want := range map[string]string{
+			"Access-Control-Allow-Origin":      "https://shop.example.com",
+			"Access-Control-Allow-Credentials": "true",
+			"Access-Control-Allow-Methods":     "GET, POST",
+			"Access-Control-Allow-Headers":     "Content-Type, Authorization",
+			"Access-Control-Max-Age":           "600",
+			"Access-Control-Expose-Headers":    "",
+		} {
+			if got := w.Header().Get(name); got != want {
+				t.Errorf("%s = %q, want %q", name, got, want)
+			}
+		}
+		vary := w.Header().Values("Vary")
+		for _, want := range []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"} {
+			if !slices.Contains(vary, want) {
+				t.Errorf("Vary = %v, missing %s", vary, want)
+			}
+		}
+	})
+
+	t.Run("refused", func(t *testing.T) {
+		w := corsRequest(NewCORS(policy), http.MethodOptions, "/api/orders", "https://evil.example.com", true)
+
+		if w.Code != http.StatusNoContent {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusNoContent)
+		}
+		for _, name := range []string{"Access-Control-Allow-Origin", "Access-Control-Allow-Credentials", "Access-Control-Allow-Methods", "Access-Control-Allow-Headers"} {
+			if got := w.Header().Get(name); got != "" {
+				t.Errorf("%s = %q on a refused preflight", name, got)
+			}
+		}
+	})
+
+	t.Run("exposed headers on actual requests", func(t *testing.T) {
+		w := corsRequest(NewCORS(policy), http.MethodGet, "/api/orders", "https://shop.example.com", false)
+
+		if got := w.Header().Get("Access-Control-Expose-Headers"); got != "X-Request-ID" {
+			t.Errorf("Access-Control-Expose-Headers = %q, want X-Request-ID", got)
+		}
+	})
+}
+
+func TestCORSOverride(t *testing.T) {
+	cors := NewCORS(CORSPolicy{AllowedOrigins: []string{"*"}}).
+		Override("/api/admin", CORSPolicy{AllowedOrigins: []string{"https://admin.example.com"}, AllowCredentials: true})
+
+	tests := []struct {
+		path       string
+		origin     string
+		wantOrigin string
+	}{
+		{"/api/products", "https://evil.example.com", "*"},
+		{"/api/admin", "https://evil.example.com", ""},
+		{"/api/admin/users", "https://evil.example.com", ""},
+		{"/api/admin/users", "https://admin.example.com", "https://admin.example.com"},
+		// A shared prefix is not a path match
+		{"/api/administrators", "https://evil.example.com", "*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path+" "+tt.origin, func(t *testing.T) {
+			w := corsRequest(cors, http.MethodOptions, tt.path, tt.origin, true)
+
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+)
+
+// APIKey represents a long-lived credential for server-to-server integrations.
+// Only a hash of the key is stored; Prefix is kept in clear so keys can be
+// told apart. The key acts as its owning user, limited to its scopes.
+type APIKey struct {
+	ID          uint         `gorm:"primaryKey" json:"id"`
+	Name        string       `gorm:"not null" json:"name"`
+	UserID      uint         `gorm:"index;not null" json:"user_id"`
+	Prefix      string       `gorm:"uniqueIndex;not null" json:"prefix"`
+	KeyHash     string       `gorm:"uniqueIndex;not null" json:"-"`
+	Scopes      []Permission `gorm:"many2many:api_key_scopes" json:"scopes"`
+	CreatedByID uint         `json:"created_by_id"`
+	ExpiresAt   *time.Time   `json:"expires_at"`
+	LastUsedAt  *time.Time   `json:"last_used_at"`
+	RevokedAt   *time.Time   `json:"revoked_at"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+// ScopeNames returns the names of the key's scopes
+func (k *APIKey) ScopeNames() []string {
+	names := make([]string, 0, len(k.Scopes))
+	for _, scope := range k.Scopes {
+		names = append(names, scope.Name)
+	}
+	return names
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditEvent records a security-relevant action. Rows are append-only and
+// each one carries the hash of the one before it, so editing or deleting a
+// row breaks the chain.
+type AuditEvent struct {
+	ID      uint  `gorm:"primaryKey" json:"id"`
+	ActorID *uint `gorm:"index" json:"actor_id"` // nil for anonymous requests
+	// ImpersonatorID is the admin really behind the request when ActorID is being impersonated
+	ImpersonatorID *uint           `gorm:"index" json:"impersonator_id,omitempty"`
+	Action         string          `gorm:"index;not null" json:"action"`
+	TargetType     string          `gorm:"index:idx_audit_target" json:"target_type"`
+	TargetID       string          `gorm:"index:idx_audit_target" json:"target_id"`
+	IP             string          `json:"ip"`
+	UserAgent      string          `json:"user_agent"`
+	RequestID      string          `gorm:"index" json:"request_id"`
+	Diff           json.RawMessage `gorm:"type:json" json:"diff,omitempty"`
+	PrevHash       string          `json:"prev_hash"`
+	Hash           string          `gorm:"uniqueIndex;not null" json:"hash"`
+	CreatedAt      time.Time       `gorm:"index" json:"created_at"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// DataExport represents a request for an archive of everything stored about
+// a user. The archive is built in the background and downloaded with a
+// single link; only a hash of the link token is stored.
+type DataExport struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	UserID        uint       `gorm:"index;not null" json:"user_id"`
+	RequestedByID uint       `json:"requested_by_id"`
+	Status        string     `gorm:"default:pending" json:"status"` // pending, ready, failed, expired
+	FilePath      string     `json:"-"`
+	TokenHash     string     `gorm:"index" json:"-"`
+	Error         string     `json:"error,omitempty"`
+	CompletedAt   *time.Time `json:"completed_at"`
+	ExpiresAt     *time.Time `json:"expires_at"` // when the download link stops working
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
@@ -25,6 +25,10 @@ type OrderItem struct {
 	OrderID   uint      `json:"order_id"`
 	ProductID uint      `json:"product_id"`
 	Product   Product   `json:"product"`
+	VariantID *uint     `gorm:"index" json:"variant_id"`
+	Variant   *Variant  `gorm:"constraint:OnDelete:SET NULL" json:"-"`
+	SKU       string    `json:"sku"`           // copied from the variant when ordered
+	Title     string    `json:"variant_title"` // copied from the variant when ordered
 	Quantity  int       `json:"quantity"`
 	Price     float64   `json:"price"`
 	CreatedAt time.Time `json:"created_at"`
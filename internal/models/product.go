@@ -2,28 +2,34 @@ package models
 
 import (
 	"time"
-
-	"gorm.io/gorm"
 )
 
 // Product represents a product in the catalog
 type Product struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	Name        string    `gorm:"not null" json:"name"`
-	Description string    `json:"description"`
-	Price       float64   `gorm:"not null" json:"price"`
-	Stock       int       `gorm:"not null" json:"stock"`
-	CategoryID  uint      `json:"category_id"`
-	Category    Category  `json:"category"`
-	Images      []Image   `json:"images"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          uint         `gorm:"primaryKey" json:"id"`
+	Name        string       `gorm:"not null" json:"name"`
+	Description string       `json:"description"`
+	Price       float64      `gorm:"not null" json:"price"`
+	Stock       int          `gorm:"not null" json:"stock"` // total stock of all variants
+	CategoryID  uint         `json:"category_id"`
+	Category    Category     `json:"category"`
+	Images      []Image      `json:"images"`
+	Options     []OptionType `gorm:"constraint:OnDelete:CASCADE" json:"options,omitempty"`
+	Variants    []Variant    `gorm:"constraint:OnDelete:CASCADE" json:"variants,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
 }
 
-// Category represents a product category
+// Category represents a product category. Categories nest under a parent;
+// Path lists the IDs from the root down to the category itself, e.g. /1/4/9/,
+// so a subtree is every category whose path starts with its root's path.
 type Category struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
 	Name      string    `gorm:"not null" json:"name"`
+	Slug      string    `gorm:"uniqueIndex" json:"slug"`
+	ParentID  *uint     `gorm:"index" json:"parent_id"`
+	Path      string    `json:"path"`
+	Position  int       `gorm:"not null;default:0" json:"position"` // order among siblings
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -33,6 +39,7 @@ type Image struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
 	URL       string    `gorm:"not null" json:"url"`
 	ProductID uint      `json:"product_id"`
+	VariantID *uint     `gorm:"index" json:"variant_id"` // set for images of a single variant
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
\ No newline at end of file
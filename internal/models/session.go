@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// Session is one logged-in device. Its ID is the family ID of the refresh
+// tokens it has been issued, and access tokens carry it as their sid claim.
+type Session struct {
+	ID         string     `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"index;not null" json:"user_id"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `gorm:"index" json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
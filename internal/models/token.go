@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+)
+
+// RefreshToken represents a single-use refresh token. Only a hash of the token
+// is stored. Tokens obtained by rotating one another share a FamilyID.
+type RefreshToken struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	UserID          uint       `gorm:"index;not null" json:"user_id"`
+	FamilyID        string     `gorm:"index;not null" json:"family_id"`
+	TokenHash       string     `gorm:"uniqueIndex;not null" json:"-"`
+	AccessTokenID   string     `gorm:"index" json:"-"` // jti of the access token issued alongside
+	AccessExpiresAt time.Time  `json:"-"`
+	ExpiresAt       time.Time  `json:"expires_at"`
+	UsedAt          *time.Time `json:"used_at"`
+	RevokedAt       *time.Time `json:"revoked_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// RevokedToken records the jti of an access token that must no longer be accepted
+type RevokedToken struct {
+	JTI       string    `gorm:"primaryKey" json:"jti"`
+	ExpiresAt time.Time `gorm:"index" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PasswordResetToken represents a single-use password reset token. Only a
+// hash of the token is stored.
+type PasswordResetToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"index;not null" json:"user_id"`
+	TokenHash string     `gorm:"uniqueIndex;not null" json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// LoginAttempt tracks recent failed logins for one throttling key, such as an
+// account email or a client IP
+type LoginAttempt struct {
+	Key           string    `gorm:"primaryKey" json:"key"`
+	Failures      int       `gorm:"not null" json:"failures"`
+	LastFailureAt time.Time `gorm:"index" json:"last_failure_at"`
+	LockedUntil   time.Time `json:"locked_until"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
@@ -1,37 +1,75 @@
 package models
 
 import (
+	"sync"
 	"time"
 
-	"golang.org/x/crypto/bcrypt"
-	"gorm.io/gorm"
+	"github.com/yourusername/ecommerce/internal/password"
+)
+
+// dummyPasswordHash is compared against when there is no user, so that a
+// login for an unknown email takes as long as one with a wrong password. It
+// is made on first use so it has the configured scheme and cost.
+var (
+	dummyPasswordHash     string
+	dummyPasswordHashOnce sync.Once
 )
 
 // User represents a user in the system
 type User struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	Email     string    `gorm:"uniqueIndex;not null" json:"email"`
-	Password  string    `gorm:"not null" json:"-"`
-	FirstName string    `json:"first_name"`
-	LastName  string    `json:"last_name"`
-	Role      string    `gorm:"default:user" json:"role"` // user, admin
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	Email           string     `gorm:"uniqueIndex;not null" json:"email"`
+	Password        string     `gorm:"not null" json:"-"`
+	FirstName       string     `json:"first_name"`
+	LastName        string     `json:"last_name"`
+	Role            string     `gorm:"default:user" json:"role"` // user, admin
+	Disabled        bool       `gorm:"not null;default:false" json:"disabled"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	TOTPSecret      string     `json:"-"`
+	TOTPEnabledAt   *time.Time `json:"-"`
+	TOTPLastStep    int64      `json:"-"`
+	AnonymizedAt    *time.Time `json:"anonymized_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
 }
 
-// BeforeSave hashes the password before saving to the database
-func (u *User) BeforeSave(tx *gorm.DB) error {
-	if u.Password != "" {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
-		if err != nil {
-			return err
-		}
-		u.Password = string(hashedPassword)
+// SetPassword hashes a plain password and stores the hash on the user. It is
+// the only way a password is set; saving a user never hashes anything.
+func (u *User) SetPassword(plain string) error {
+	hashedPassword, err := password.Hash(plain)
+	if err != nil {
+		return err
 	}
+	u.Password = hashedPassword
 	return nil
 }
 
+// MFAEnabled reports whether the user has completed two-factor enrollment
+func (u *User) MFAEnabled() bool {
+	return u.TOTPEnabledAt != nil
+}
+
 // ComparePassword checks if the provided password matches the stored hash
-func (u *User) ComparePassword(password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
-}
\ No newline at end of file
+func (u *User) ComparePassword(plain string) error {
+	ok, err := password.Verify(plain, u.Password)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return password.ErrMismatch
+	}
+	return nil
+}
+
+// PasswordNeedsRehash reports whether the stored hash uses an outdated scheme or cost
+func (u *User) PasswordNeedsRehash() bool {
+	return password.NeedsRehash(u.Password)
+}
+
+// CompareDummyPassword does the work of ComparePassword without matching any user
+func CompareDummyPassword(plain string) {
+	dummyPasswordHashOnce.Do(func() {
+		dummyPasswordHash, _ = password.Hash("dummy password for timing")
+	})
+	password.Verify(plain, dummyPasswordHash)
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+)
+
+// OptionType is a way a product varies, such as size or color
+type OptionType struct {
+	ID        uint          `gorm:"primaryKey" json:"id"`
+	ProductID uint          `gorm:"not null;index" json:"product_id"`
+	Name      string        `gorm:"not null" json:"name"`
+	Position  int           `gorm:"not null;default:0" json:"position"`
+	Values    []OptionValue `gorm:"constraint:OnDelete:CASCADE" json:"values"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// OptionValue is one choice of an option type, such as "M" or "Red"
+type OptionValue struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	OptionTypeID uint      `gorm:"not null;index" json:"option_type_id"`
+	Value        string    `gorm:"not null" json:"value"`
+	Position     int       `gorm:"not null;default:0" json:"position"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Variant is a purchasable version of a product with one value of each of
+// its option types. A product without options has a single variant with none.
+type Variant struct {
+	ID        uint          `gorm:"primaryKey" json:"id"`
+	ProductID uint          `gorm:"not null;index" json:"product_id"`
+	SKU       string        `gorm:"uniqueIndex;not null" json:"sku"`
+	Title     string        `json:"title"` // option values joined, e.g. "M / Red"
+	Price     *float64      `json:"price"` // overrides the product's price when set
+	Stock     int           `gorm:"not null" json:"stock"`
+	Options   []OptionValue `gorm:"many2many:variant_option_values;constraint:OnDelete:CASCADE" json:"options"`
+	Images    []Image       `gorm:"constraint:OnDelete:CASCADE" json:"images"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// PriceFor returns the variant's price, falling back to its product's price
+func (v Variant) PriceFor(product Product) float64 {
+	if v.Price != nil {
+		return *v.Price
+	}
+	return product.Price
+}
@@ -0,0 +1,351 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/yourusername/ecommerce/configs"
+	"github.com/yourusername/ecommerce/internal/auth"
+)
+
+const (
+	testClientID    = "shop"
+	testRedirectURL = "https://shop.example.com/api/auth/oidc/mock/callback"
+)
+
+// mockIssuer is a local OpenID Connect issuer serving discovery, JWKS and a
+// token endpoint that returns an ID token built from its claims
+type mockIssuer struct {
+	server *httptest.Server
+
+	mu              sync.Mutex
+	issuer          string // returned in discovery, defaults to the server URL
+	kid             string
+	key             ed25519.PrivateKey
+	signingKey      ed25519.PrivateKey // signs ID tokens, defaults to key
+	claims          jwt.MapClaims
+	discoveryHits   int
+	jwksHits        int
+	lastTokenParams url.Values
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	t.Helper()
+	m := &mockIssuer{}
+	m.rotateKey(t, "key-1")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.discoveryHits++
+		issuer := m.issuer
+		if issuer == "" {
+			issuer = m.server.URL
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 issuer,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.jwksHits++
+		json.NewEncoder(w).Encode(auth.JWKSet{Keys: []auth.JWK{{
+			KeyType:   "OKP",
+			KeyID:     m.kid,
+			Use:       "sig",
+			Algorithm: "EdDSA",
+			Curve:     "Ed25519",
+			X:         base64.RawURLEncoding.EncodeToString(m.key.Public().(ed25519.PublicKey)),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.lastTokenParams = r.PostForm
+
+		token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, m.claims)
+		token.Header["kid"] = m.kid
+		signed, err := token.SignedString(m.signingKey)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": signed})
+	})
+
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+// rotateKey replaces the issuer's signing key
+func (m *mockIssuer) rotateKey(t *testing.T, kid string) {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.kid, m.key, m.signingKey = kid, key, key
+}
+
+// issue sets the claims of the next ID token, starting from a valid set
+func (m *mockIssuer) issue(nonce string, overrides jwt.MapClaims) {
+	claims := jwt.MapClaims{
+		"iss":            m.server.URL,
+		"aud":            testClientID,
+		"sub":            "user-123",
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          nonce,
+		"email":          "Jane.Doe@Example.com",
+		"email_verified": true,
+	}
+	for k, v := range overrides {
+		if v == nil {
+			delete(claims, k)
+			continue
+		}
+		claims[k] = v
+	}
+	m.mu.Lock()
+	m.claims = claims
+	m.mu.Unlock()
+}
+
+func (m *mockIssuer) provider() *Provider {
+	return NewProvider(&configs.OIDCProvider{
+		Name:        "mock",
+		IssuerURL:   m.server.URL,
+		ClientID:    testClientID,
+		RedirectURL: testRedirectURL,
+		Scopes:      []string{"openid", "email", "profile"},
+	})
+}
+
+func TestAuthCodeURLUsesDiscovery(t *testing.T) {
+	issuer := newMockIssuer(t)
+	provider := issuer.provider()
+	ctx := context.Background()
+
+	authURL, err := provider.AuthCodeURL(ctx, "the-state", "the-nonce", CodeChallenge("verifier"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := parsed.Scheme+"://"+parsed.Host+parsed.Path, issuer.server.URL+"/authorize"; got != want {
+		t.Errorf("authorization endpoint = %s, want %s", got, want)
+	}
+	query := parsed.Query()
+	for param, want := range map[string]string{
+		"response_type":         "code",
+		"client_id":             testClientID,
+		"redirect_uri":          testRedirectURL,
+		"scope":                 "openid email profile",
+		"state":                 "the-state",
+		"nonce":                 "the-nonce",
+		"code_challenge":        CodeChallenge("verifier"),
+		"code_challenge_method": "S256",
+	} {
+		if got := query.Get(param); got != want {
+			t.Errorf("%s = %q, want %q", param, got, want)
+		}
+	}
+
+	// The discovery document is fetched once and cached
+	if _, err := provider.AuthCodeURL(ctx, "s", "n", "c"); err != nil {
+		t.Fatal(err)
+	}
+	if issuer.discoveryHits != 1 {
+		t.Errorf("discovery fetched %d times, want 1", issuer.discoveryHits)
+	}
+}
+
+func TestDiscoveryRejectsIssuerMismatch(t *testing.T) {
+	issuer := newMockIssuer(t)
+	issuer.issuer = "https://evil.example.com"
+
+	if _, err := issuer.provider().AuthCodeURL(context.Background(), "s", "n", "c"); err == nil {
+		t.Fatal("discovery document for another issuer was accepted")
+	}
+}
+
+func TestExchange(t *testing.T) {
+	issuer := newMockIssuer(t)
+	provider := issuer.provider()
+	issuer.issue("the-nonce", nil)
+
+	claims, err := provider.Exchange(context.Background(), "the-code", "the-verifier", "the-nonce")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "user-123" {
+		t.Errorf("subject = %q, want user-123", claims.Subject)
+	}
+
+	params := issuer.lastTokenParams
+	for param, want := range map[string]string{
+		"grant_type":    "authorization_code",
+		"code":          "the-code",
+		"code_verifier": "the-verifier",
+		"redirect_uri":  testRedirectURL,
+		"client_id":     testClientID,
+	} {
+		if got := params.Get(param); got != want {
+			t.Errorf("token request %s = %q, want %q", param, got, want)
+		}
+	}
+}
+
+func TestExchangeRejectsInvalidIDTokens(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides jwt.MapClaims
+		nonce     string
+	}{
+		{name: "nonce mismatch", overrides: jwt.MapClaims{"nonce": "someone-elses-nonce"}},
+		{name: "missing nonce", overrides: jwt.MapClaims{"nonce": nil}},
+		{name: "expired", overrides: jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}},
+		{name: "no expiry", overrides: jwt.MapClaims{"exp": nil}},
+		{name: "wrong issuer", overrides: jwt.MapClaims{"iss": "https://evil.example.com"}},
+		{name: "wrong audience", overrides: jwt.MapClaims{"aud": "another-client"}},
+		{name: "multiple audiences without azp", overrides: jwt.MapClaims{"aud": []string{testClientID, "another-client"}}},
+		{name: "no subject", overrides: jwt.MapClaims{"sub": nil}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issuer := newMockIssuer(t)
+			issuer.issue("the-nonce", tt.overrides)
+
+			if _, err := issuer.provider().Exchange(context.Background(), "code", "verifier", "the-nonce"); err == nil {
+				t.Fatal("invalid ID token was accepted")
+			}
+		})
+	}
+}
+
+func TestExchangeRefetchesJWKSOnKeyRotation(t *testing.T) {
+	issuer := newMockIssuer(t)
+	provider := issuer.provider()
+	ctx := context.Background()
+
+	issuer.issue("n1", nil)
+	if _, err := provider.Exchange(ctx, "code", "verifier", "n1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A second login with the same key uses the cached key set
+	issuer.issue("n2", nil)
+	if _, err := provider.Exchange(ctx, "code", "verifier", "n2"); err != nil {
+		t.Fatal(err)
+	}
+	if issuer.jwksHits != 1 {
+		t.Fatalf("JWKS fetched %d times before rotation, want 1", issuer.jwksHits)
+	}
+
+	issuer.rotateKey(t, "key-2")
+	issuer.issue("n3", nil)
+	if _, err := provider.Exchange(ctx, "code", "verifier", "n3"); err != nil {
+		t.Fatalf("token signed with rotated key rejected: %v", err)
+	}
+	if issuer.jwksHits != 2 {
+		t.Errorf("JWKS fetched %d times after rotation, want 2", issuer.jwksHits)
+	}
+}
+
+func TestExchangeRejectsUnknownSigningKey(t *testing.T) {
+	issuer := newMockIssuer(t)
+	_, forged, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer.signingKey = forged
+	issuer.issue("the-nonce", nil)
+
+	if _, err := issuer.provider().Exchange(context.Background(), "code", "verifier", "the-nonce"); err == nil {
+		t.Fatal("ID token with a signature from an unpublished key was accepted")
+	}
+}
+
+func TestCheckState(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		returned  string
+		cookie    string
+		expiresAt time.Time
+		want      error
+	}{
+		{"valid", "abc", "abc", now.Add(time.Minute), nil},
+		{"expired", "abc", "abc", now.Add(-time.Second), ErrStateExpired},
+		{"expires now", "abc", "abc", now, ErrStateExpired},
+		{"no cookie", "abc", "", now.Add(time.Minute), ErrStateMismatch},
+		{"another browser's state", "abc", "xyz", now.Add(time.Minute), ErrStateMismatch},
+		{"empty state and cookie", "", "", now.Add(time.Minute), ErrStateMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CheckState(tt.returned, tt.cookie, tt.expiresAt, now); got != tt.want {
+				t.Errorf("CheckState() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifiedEmail(t *testing.T) {
+	tests := []struct {
+		name    string
+		claims  string
+		want    string
+		wantErr bool
+	}{
+		{"normalized for linking", `{"email": " Jane.Doe@Example.com ", "email_verified": true}`, "jane.doe@example.com", false},
+		{"verified as string", `{"email": "jane@example.com", "email_verified": "true"}`, "jane@example.com", false},
+		{"unverified", `{"email": "jane@example.com", "email_verified": false}`, "", true},
+		{"unverified as string", `{"email": "jane@example.com", "email_verified": "false"}`, "", true},
+		{"verification missing", `{"email": "jane@example.com"}`, "", true},
+		{"no email", `{"email_verified": true}`, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var claims IDTokenClaims
+			if err := json.NewDecoder(strings.NewReader(tt.claims)).Decode(&claims); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := VerifiedEmail(&claims)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifiedEmail() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("VerifiedEmail() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
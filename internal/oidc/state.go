@@ -0,0 +1,43 @@
+package oidc
+
+import (
+	"crypto/subtle"
+	"errors"
+	"strings"
+	"time"
+)
+
+// StateCookie is the cookie that ties a login's state to the browser that
+// started it, so a callback URL from someone else's login is refused
+const StateCookie = "oidc_state"
+
+var (
+	// ErrStateMismatch is returned when the callback's state wasn't issued to this browser
+	ErrStateMismatch = errors.New("login state does not match this browser")
+	// ErrStateExpired is returned when the login took longer than the state's lifetime
+	ErrStateExpired = errors.New("login state has expired")
+	// ErrUnverifiedEmail is returned when the ID token has no verified email to link or create an account with
+	ErrUnverifiedEmail = errors.New("provider email is not verified")
+)
+
+// CheckState verifies that the state returned to the callback is the one
+// stored in the browser's state cookie and that it hasn't expired
+func CheckState(returned, cookie string, expiresAt, now time.Time) error {
+	if returned == "" || subtle.ConstantTimeCompare([]byte(returned), []byte(cookie)) != 1 {
+		return ErrStateMismatch
+	}
+	if !now.Before(expiresAt) {
+		return ErrStateExpired
+	}
+	return nil
+}
+
+// VerifiedEmail returns the ID token's email, normalized for matching
+// against existing accounts, if the provider has verified it
+func VerifiedEmail(claims *IDTokenClaims) (string, error) {
+	email := strings.ToLower(strings.TrimSpace(claims.Email))
+	if email == "" || !bool(claims.EmailVerified) {
+		return "", ErrUnverifiedEmail
+	}
+	return email, nil
+}
@@ -0,0 +1,96 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func testCursor(t *testing.T) Cursor {
+	t.Helper()
+	cursor, err := Keyset{{Column: "price", Type: KeyFloat}, {Column: "id", Type: KeyInt}}.
+		Cursor("price", []interface{}{19.99, 42}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cursor
+}
+
+func TestSignerRoundTrip(t *testing.T) {
+	signer := NewSigner("cursor-secret")
+	cursor := testCursor(t)
+	cursor.Backward = true
+
+	token, err := signer.Encode(cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := signer.Decode(token, "price")
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if decoded.Sort != "price" || !decoded.Backward || len(decoded.Values) != 2 ||
+		string(decoded.Values[0]) != "19.99" || string(decoded.Values[1]) != "42" {
+		t.Errorf("Decode() = %+v, want the encoded cursor", decoded)
+	}
+}
+
+func TestSignerRejectsInvalidTokens(t *testing.T) {
+	signer := NewSigner("cursor-secret")
+	token, err := signer.Encode(testCursor(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, signature, _ := strings.Cut(token, ".")
+
+	// A forged position with a valid-looking layout but no valid signature
+	forged, _ := json.Marshal(Cursor{Sort: "price", Values: []json.RawMessage{json.RawMessage("0"), json.RawMessage("1")}})
+	forgedPayload := base64.RawURLEncoding.EncodeToString(forged)
+
+	otherKeyToken, err := NewSigner("another-secret").Encode(testCursor(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		sort  string
+	}{
+		{"empty", "", "price"},
+		{"no signature", payload, "price"},
+		{"empty signature", payload + ".", "price"},
+		{"truncated signature", payload + "." + signature[:len(signature)-1], "price"},
+		{"truncated payload", payload[:len(payload)-2] + "." + signature, "price"},
+		{"tampered payload", forgedPayload + "." + signature, "price"},
+		{"tampered signature", payload + "." + strings.ToUpper(signature), "price"},
+		{"signed with another key", otherKeyToken, "price"},
+		{"another sort order", token, "name"},
+		{"payload is not base64", "!!!." + signature, "price"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := signer.Decode(tt.token, tt.sort); err != ErrInvalidCursor {
+				t.Errorf("Decode() error = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
+
+func TestSignerRejectsMalformedPayloadWithValidSignature(t *testing.T) {
+	signer := NewSigner("cursor-secret")
+
+	for name, payload := range map[string]string{
+		"not json":  "not json",
+		"truncated": `{"s":"price","v":[19.99`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+			if _, err := signer.Decode(encoded+"."+signer.sign(encoded), "price"); err != ErrInvalidCursor {
+				t.Errorf("Decode() error = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
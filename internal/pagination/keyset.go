@@ -0,0 +1,136 @@
+package pagination
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// KeyType says how a sort key's value is decoded from a cursor
+type KeyType int
+
+// Sort key value types
+const (
+	KeyInt KeyType = iota
+	KeyFloat
+	KeyString
+	KeyTime
+)
+
+// Key is one column of a keyset sort order
+type Key struct {
+	Column     string // SQL expression
+	Descending bool
+	Type       KeyType
+}
+
+// Keyset is a sort order whose last key is unique, such as the primary key,
+// so every row has a distinct position
+type Keyset []Key
+
+// Order returns the ORDER BY clause, reversed when reading backward
+func (k Keyset) Order(backward bool) string {
+	clauses := make([]string, len(k))
+	for i, key := range k {
+		direction := "ASC"
+		if key.Descending != backward {
+			direction = "DESC"
+		}
+		clauses[i] = key.Column + " " + direction
+	}
+	return strings.Join(clauses, ", ")
+}
+
+// Cursor returns a cursor pointing past a row with the given sort key values
+func (k Keyset) Cursor(sort string, values []interface{}, backward bool) (Cursor, error) {
+	cursor := Cursor{Sort: sort, Backward: backward}
+	for _, value := range values {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return cursor, err
+		}
+		cursor.Values = append(cursor.Values, encoded)
+	}
+	return cursor, nil
+}
+
+// Seek returns a condition selecting the rows that come after a cursor's
+// position in its direction: for keys a, b, id that is
+// (a > ?) OR (a = ? AND b > ?) OR (a = ? AND b = ? AND id > ?), with the
+// comparisons flipped for descending keys and backward cursors.
+func (k Keyset) Seek(cursor Cursor) (string, []interface{}, error) {
+	if len(cursor.Values) != len(k) {
+		return "", nil, ErrInvalidCursor
+	}
+
+	values := make([]interface{}, len(k))
+	for i, key := range k {
+		value, err := decodeValue(key.Type, cursor.Values[i])
+		if err != nil {
+			return "", nil, ErrInvalidCursor
+		}
+		values[i] = value
+	}
+
+	var alternatives []string
+	var args []interface{}
+	for i, key := range k {
+		var terms []string
+		for j := 0; j < i; j++ {
+			terms = append(terms, k[j].Column+" = ?")
+			args = append(args, values[j])
+		}
+
+		operator := ">"
+		if key.Descending != cursor.Backward {
+			operator = "<"
+		}
+		terms = append(terms, key.Column+" "+operator+" ?")
+		args = append(args, values[i])
+
+		alternatives = append(alternatives, "("+strings.Join(terms, " AND ")+")")
+	}
+
+	return "(" + strings.Join(alternatives, " OR ") + ")", args, nil
+}
+
+// decodeValue reads a sort key value of the given type
+func decodeValue(keyType KeyType, raw json.RawMessage) (interface{}, error) {
+	switch keyType {
+	case KeyInt:
+		var value int64
+		err := json.Unmarshal(raw, &value)
+		return value, err
+	case KeyFloat:
+		var value float64
+		err := json.Unmarshal(raw, &value)
+		return value, err
+	case KeyTime:
+		var value time.Time
+		err := json.Unmarshal(raw, &value)
+		return value, err
+	default:
+		var value string
+		err := json.Unmarshal(raw, &value)
+		return value, err
+	}
+}
+
+// Trim takes rows fetched with a limit of limit+1 in the cursor's direction
+// and returns the page in display order, along with whether there are pages
+// after and before it. cursor is nil on the first page.
+func Trim[T any](rows []T, limit int, cursor *Cursor) ([]T, bool, bool) {
+	more := len(rows) > limit
+	if more {
+		rows = rows[:limit]
+	}
+
+	if cursor == nil || !cursor.Backward {
+		return rows, more, cursor != nil
+	}
+
+	for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+		rows[i], rows[j] = rows[j], rows[i]
+	}
+	return rows, true, more
+}
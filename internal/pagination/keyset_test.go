@@ -0,0 +1,239 @@
+package pagination
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+)
+
+// row is a listing row sorted by price descending, then id ascending
+type row struct {
+	id    int64
+	price float64
+}
+
+var priceKeyset = Keyset{
+	{Column: "price", Descending: true, Type: KeyFloat},
+	{Column: "id", Type: KeyInt},
+}
+
+func (r row) values() []interface{} { return []interface{}{r.price, r.id} }
+
+// after evaluates the condition Seek builds for priceKeyset against a row,
+// the way the database would
+func after(r row, cursor Cursor) bool {
+	var price float64
+	var id int64
+	json.Unmarshal(cursor.Values[0], &price)
+	json.Unmarshal(cursor.Values[1], &id)
+
+	if cursor.Backward {
+		return r.price > price || (r.price == price && r.id < id)
+	}
+	return r.price < price || (r.price == price && r.id > id)
+}
+
+// fetch returns up to limit+1 rows past the cursor in its direction, like the
+// handlers' queries do
+func fetch(rows []row, cursor *Cursor, limit int) []row {
+	ordered := slices.Clone(rows)
+	backward := cursor != nil && cursor.Backward
+	sort.Slice(ordered, func(i, j int) bool {
+		a, b := ordered[i], ordered[j]
+		if backward {
+			a, b = b, a
+		}
+		if a.price != b.price {
+			return a.price > b.price
+		}
+		return a.id < b.id
+	})
+
+	var page []row
+	for _, r := range ordered {
+		if cursor == nil || after(r, *cursor) {
+			page = append(page, r)
+		}
+		if len(page) == limit+1 {
+			break
+		}
+	}
+	return page
+}
+
+func ids(rows []row) []int64 {
+	result := make([]int64, len(rows))
+	for i, r := range rows {
+		result[i] = r.id
+	}
+	return result
+}
+
+func TestKeysetOrder(t *testing.T) {
+	if got, want := priceKeyset.Order(false), "price DESC, id ASC"; got != want {
+		t.Errorf("Order(false) = %q, want %q", got, want)
+	}
+	if got, want := priceKeyset.Order(true), "price ASC, id DESC"; got != want {
+		t.Errorf("Order(true) = %q, want %q", got, want)
+	}
+}
+
+func TestKeysetSeek(t *testing.T) {
+	cursor, err := priceKeyset.Cursor("price", []interface{}{9.5, 7}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	condition, args, err := priceKeyset.Seek(cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "((price < ?) OR (price = ? AND id > ?))"; condition != want {
+		t.Errorf("forward condition = %q, want %q", condition, want)
+	}
+	if got, want := fmt.Sprint(args), "[9.5 9.5 7]"; got != want {
+		t.Errorf("forward args = %s, want %s", got, want)
+	}
+	if _, ok := args[2].(int64); !ok {
+		t.Errorf("id argument is %T, want int64", args[2])
+	}
+
+	cursor.Backward = true
+	condition, _, err = priceKeyset.Seek(cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "((price > ?) OR (price = ? AND id < ?))"; condition != want {
+		t.Errorf("backward condition = %q, want %q", condition, want)
+	}
+}
+
+func TestKeysetSeekDecodesTimes(t *testing.T) {
+	keyset := Keyset{{Column: "created_at", Descending: true, Type: KeyTime}, {Column: "id", Descending: true, Type: KeyInt}}
+	createdAt := time.Date(2026, 3, 1, 12, 30, 0, 123456789, time.UTC)
+
+	cursor, err := keyset.Cursor("newest", []interface{}{createdAt, 3}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, args, err := keyset.Seek(cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := args[0].(time.Time); !ok || !got.Equal(createdAt) {
+		t.Errorf("time argument = %v, want %v", args[0], createdAt)
+	}
+}
+
+func TestKeysetSeekRejectsMismatchedCursors(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []json.RawMessage
+	}{
+		{"too few values", []json.RawMessage{json.RawMessage("9.5")}},
+		{"too many values", []json.RawMessage{json.RawMessage("9.5"), json.RawMessage("7"), json.RawMessage("1")}},
+		{"string for a number", []json.RawMessage{json.RawMessage(`"9.5"`), json.RawMessage("7")}},
+		{"fraction for an integer", []json.RawMessage{json.RawMessage("9.5"), json.RawMessage("7.5")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := priceKeyset.Seek(Cursor{Sort: "price", Values: tt.values}); err != ErrInvalidCursor {
+				t.Errorf("Seek() error = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
+
+// TestPagingWithTies walks a listing where many rows share a price forward to
+// the end and back to the start, checking that every row is seen exactly once
+// and page boundaries falling inside a run of ties neither skip nor repeat rows
+func TestPagingWithTies(t *testing.T) {
+	rows := []row{
+		{1, 30}, {2, 20}, {3, 20}, {4, 20}, {5, 20}, {6, 10},
+		{7, 20}, {8, 10}, {9, 30}, {10, 5}, {11, 20},
+	}
+	want := ids(fetch(rows, nil, len(rows)))
+
+	for limit := 1; limit <= len(rows)+1; limit++ {
+		t.Run(fmt.Sprintf("limit %d", limit), func(t *testing.T) {
+			var pages [][]row
+			var cursor *Cursor
+			for {
+				page, hasNext, hasPrev := Trim(fetch(rows, cursor, limit), limit, cursor)
+				if hasPrev != (cursor != nil) {
+					t.Fatalf("page %d: hasPrev = %v", len(pages)+1, hasPrev)
+				}
+				pages = append(pages, page)
+				if !hasNext {
+					break
+				}
+				next, err := priceKeyset.Cursor("price", page[len(page)-1].values(), false)
+				if err != nil {
+					t.Fatal(err)
+				}
+				cursor = &next
+			}
+
+			var seen []int64
+			for _, page := range pages {
+				seen = append(seen, ids(page)...)
+			}
+			if !slices.Equal(seen, want) {
+				t.Fatalf("forward pages = %v, want %v", seen, want)
+			}
+
+			// Walk back from the last page using prev cursors
+			for i := len(pages) - 1; i > 0; i-- {
+				prev, err := priceKeyset.Cursor("price", pages[i][0].values(), true)
+				if err != nil {
+					t.Fatal(err)
+				}
+				page, hasNext, hasPrev := Trim(fetch(rows, &prev, limit), limit, &prev)
+				if !slices.Equal(ids(page), ids(pages[i-1])) {
+					t.Errorf("page %d read backward = %v, want %v", i, ids(page), ids(pages[i-1]))
+				}
+				if !hasNext {
+					t.Errorf("page %d read backward: hasNext = false", i)
+				}
+				if hasPrev != (i > 1) {
+					t.Errorf("page %d read backward: hasPrev = %v, want %v", i, hasPrev, i > 1)
+				}
+			}
+		})
+	}
+}
+
+func TestTrim(t *testing.T) {
+	forward := &Cursor{}
+	backward := &Cursor{Backward: true}
+
+	tests := []struct {
+		name     string
+		rows     []int
+		cursor   *Cursor
+		want     []int
+		wantNext bool
+		wantPrev bool
+	}{
+		{"first page with more", []int{1, 2, 3, 4}, nil, []int{1, 2, 3}, true, false},
+		{"first page exactly full", []int{1, 2, 3}, nil, []int{1, 2, 3}, false, false},
+		{"empty listing", []int{}, nil, []int{}, false, false},
+		{"forward with more", []int{4, 5, 6, 7}, forward, []int{4, 5, 6}, true, true},
+		{"forward last page", []int{7, 8}, forward, []int{7, 8}, false, true},
+		{"backward with more", []int{6, 5, 4, 3}, backward, []int{4, 5, 6}, true, true},
+		{"backward reaching the start", []int{3, 2, 1}, backward, []int{1, 2, 3}, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, hasNext, hasPrev := Trim(slices.Clone(tt.rows), 3, tt.cursor)
+			if !slices.Equal(got, tt.want) || hasNext != tt.wantNext || hasPrev != tt.wantPrev {
+				t.Errorf("Trim() = %v, %v, %v; want %v, %v, %v", got, hasNext, hasPrev, tt.want, tt.wantNext, tt.wantPrev)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package pagination
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Link is one entry of an RFC 8288 Link header. Params are set on the
+// current request's query string; an empty value removes the parameter.
+type Link struct {
+	Rel    string
+	Params map[string]string
+}
+
+// SetLinkHeader writes links relative to the current request, such as
+// </api/products?cursor=...>; rel="next"
+func SetLinkHeader(c *gin.Context, links []Link) {
+	var entries []string
+	for _, link := range links {
+		query := c.Request.URL.Query()
+		for name, value := range link.Params {
+			if value == "" {
+				query.Del(name)
+			} else {
+				query.Set(name, value)
+			}
+		}
+
+		target := c.Request.URL.Path
+		if encoded := query.Encode(); encoded != "" {
+			target += "?" + encoded
+		}
+		entries = append(entries, fmt.Sprintf("<%s>; rel=\"%s\"", target, link.Rel))
+	}
+
+	if len(entries) > 0 {
+		c.Header("Link", strings.Join(entries, ", "))
+	}
+}
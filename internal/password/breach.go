@@ -0,0 +1,78 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// prefixLength is the number of leading hex characters hashes are bucketed
+// by, as in the k-anonymity range API of Have I Been Pwned
+const prefixLength = 5
+
+// BreachList is a set of SHA-1 hashes of passwords known from data breaches.
+// Hashes are bucketed by their first five hex characters, so the file can be
+// a download of the range API or the full list in the same HASH:COUNT format.
+type BreachList struct {
+	buckets map[string]map[string]struct{}
+}
+
+// LoadBreachList reads a file with one upper- or lower-case hex SHA-1 per
+// line, optionally followed by ":count"
+func LoadBreachList(path string) (*BreachList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	list := &BreachList{buckets: make(map[string]map[string]struct{})}
+
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		hash, _, _ := strings.Cut(line, ":")
+		hash = strings.ToUpper(hash)
+		if len(hash) != sha1.Size*2 {
+			return nil, fmt.Errorf("%s:%d: not a SHA-1 hash", path, lineNumber)
+		}
+		if _, err := hex.DecodeString(hash); err != nil {
+			return nil, fmt.Errorf("%s:%d: not a SHA-1 hash", path, lineNumber)
+		}
+
+		prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+		bucket, ok := list.buckets[prefix]
+		if !ok {
+			bucket = make(map[string]struct{})
+			list.buckets[prefix] = bucket
+		}
+		bucket[suffix] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// Contains reports whether the password's hash is on the list
+func (l *BreachList) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	bucket, ok := l.buckets[hash[:prefixLength]]
+	if !ok {
+		return false
+	}
+	_, ok = bucket[hash[prefixLength:]]
+	return ok
+}
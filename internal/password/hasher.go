@@ -0,0 +1,110 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/yourusername/ecommerce/configs"
+)
+
+var (
+	// ErrUnknownScheme is returned for a stored hash no hasher recognizes
+	ErrUnknownScheme = errors.New("unknown password hash scheme")
+	// ErrMismatch is returned when a password doesn't match its hash
+	ErrMismatch = errors.New("password does not match")
+)
+
+// Hasher hashes passwords into a self-describing PHC string and verifies them
+type Hasher interface {
+	// Hash returns the encoded hash of a password
+	Hash(password string) (string, error)
+	// Verify reports whether the password matches an encoded hash of this scheme
+	Verify(password, encoded string) (bool, error)
+	// Recognizes reports whether an encoded hash belongs to this scheme
+	Recognizes(encoded string) bool
+	// NeedsRehash reports whether an encoded hash of this scheme uses weaker
+	// parameters than the hasher is configured with
+	NeedsRehash(encoded string) bool
+}
+
+var (
+	mu        sync.RWMutex
+	preferred Hasher = NewArgon2idHasher(DefaultArgon2idParams)
+	fallbacks        = []Hasher{NewBcryptHasher(DefaultBcryptCost)}
+)
+
+// InitHasher selects the scheme new hashes use from PASSWORD_HASH_SCHEME.
+// Hashes in the other supported schemes keep verifying.
+func InitHasher(config *configs.Config) error {
+	argon2id := NewArgon2idHasher(Argon2idParams{
+		Memory:      uint32(config.Argon2Memory),
+		Iterations:  uint32(config.Argon2Iterations),
+		Parallelism: uint8(config.Argon2Parallelism),
+		SaltLength:  DefaultArgon2idParams.SaltLength,
+		KeyLength:   DefaultArgon2idParams.KeyLength,
+	})
+	if config.Argon2Memory < 0 || config.Argon2Iterations < 0 ||
+		config.Argon2Parallelism < 0 || config.Argon2Parallelism > 255 ||
+		!validArgon2idCost(argon2id.params) {
+		return fmt.Errorf("ARGON2_MEMORY, ARGON2_ITERATIONS and ARGON2_PARALLELISM must be at least 1, with at most %d iterations, at least 8 KiB of memory per lane and at most %d KiB in total", maxArgon2idIterations, maxArgon2idMemory)
+	}
+	bcrypt := NewBcryptHasher(config.BcryptCost)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	switch strings.ToLower(config.PasswordHashScheme) {
+	case "argon2id":
+		preferred, fallbacks = argon2id, []Hasher{bcrypt}
+	case "bcrypt":
+		preferred, fallbacks = bcrypt, []Hasher{argon2id}
+	default:
+		return errors.New("unsupported password hash scheme: " + config.PasswordHashScheme)
+	}
+	return nil
+}
+
+// Hash hashes a password with the preferred scheme
+func Hash(password string) (string, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	return preferred.Hash(password)
+}
+
+// Verify checks a password against a hash in any supported scheme
+func Verify(password, encoded string) (bool, error) {
+	hasher := hasherFor(encoded)
+	if hasher == nil {
+		return false, ErrUnknownScheme
+	}
+	return hasher.Verify(password, encoded)
+}
+
+// NeedsRehash reports whether a hash should be replaced because it uses
+// another scheme or weaker parameters than the preferred hasher
+func NeedsRehash(encoded string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if !preferred.Recognizes(encoded) {
+		return true
+	}
+	return preferred.NeedsRehash(encoded)
+}
+
+func hasherFor(encoded string) Hasher {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if preferred.Recognizes(encoded) {
+		return preferred
+	}
+	for _, hasher := range fallbacks {
+		if hasher.Recognizes(encoded) {
+			return hasher
+		}
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package password
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/ecommerce/configs"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testArgon2idParams are cheap enough to keep the tests fast
+var testArgon2idParams = Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+// useHashers installs a preferred scheme for the duration of a test
+func useHashers(t *testing.T, scheme string, argon2id Argon2idParams, bcryptCost int) {
+	t.Helper()
+	mu.RLock()
+	oldPreferred, oldFallbacks := preferred, fallbacks
+	mu.RUnlock()
+	t.Cleanup(func() {
+		mu.Lock()
+		preferred, fallbacks = oldPreferred, oldFallbacks
+		mu.Unlock()
+	})
+
+	err := InitHasher(&configs.Config{
+		PasswordHashScheme: scheme,
+		BcryptCost:         bcryptCost,
+		Argon2Memory:       int(argon2id.Memory),
+		Argon2Iterations:   int(argon2id.Iterations),
+		Argon2Parallelism:  int(argon2id.Parallelism),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestArgon2idRoundTrip(t *testing.T) {
+	hasher := NewArgon2idHasher(testArgon2idParams)
+
+	encoded, err := hasher.Hash("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Errorf("unexpected encoding %q", encoded)
+	}
+	if !hasher.Recognizes(encoded) {
+		t.Error("hasher does not recognize its own hash")
+	}
+
+	ok, err := hasher.Verify("correct horse battery staple", encoded)
+	if err != nil || !ok {
+		t.Errorf("Verify(correct password) = %v, %v; want true, nil", ok, err)
+	}
+	ok, err = hasher.Verify("correct horse battery stapler", encoded)
+	if err != nil || ok {
+		t.Errorf("Verify(wrong password) = %v, %v; want false, nil", ok, err)
+	}
+
+	again, err := hasher.Hash("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again == encoded {
+		t.Error("two hashes of the same password are identical; salt is not random")
+	}
+}
+
+func TestBcryptHashesAreUpgraded(t *testing.T) {
+	useHashers(t, "bcrypt", testArgon2idParams, bcrypt.MinCost)
+	legacy, err := Hash("hunter2hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(legacy, "$2a$") {
+		t.Fatalf("expected a bcrypt hash, got %q", legacy)
+	}
+
+	useHashers(t, "argon2id", testArgon2idParams, bcrypt.MinCost)
+
+	// The old hash still verifies through the fallback, but is due for an upgrade
+	ok, err := Verify("hunter2hunter2", legacy)
+	if err != nil || !ok {
+		t.Fatalf("Verify(bcrypt hash) = %v, %v; want true, nil", ok, err)
+	}
+	if !NeedsRehash(legacy) {
+		t.Error("bcrypt hash does not need a rehash after switching to argon2id")
+	}
+
+	upgraded, err := Hash("hunter2hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(upgraded, "$argon2id$") {
+		t.Fatalf("expected an argon2id hash, got %q", upgraded)
+	}
+	if NeedsRehash(upgraded) {
+		t.Error("fresh argon2id hash needs a rehash")
+	}
+	if ok, err := Verify("hunter2hunter2", upgraded); err != nil || !ok {
+		t.Errorf("Verify(argon2id hash) = %v, %v; want true, nil", ok, err)
+	}
+
+	// Raising the configured cost makes existing hashes outdated
+	stronger := testArgon2idParams
+	stronger.Iterations = 2
+	useHashers(t, "argon2id", stronger, bcrypt.MinCost)
+	if !NeedsRehash(upgraded) {
+		t.Error("argon2id hash with fewer iterations than configured does not need a rehash")
+	}
+}
+
+func TestVerifyRejectsMalformedHashes(t *testing.T) {
+	useHashers(t, "argon2id", testArgon2idParams, bcrypt.MinCost)
+
+	salt := base64.RawStdEncoding.EncodeToString([]byte("0123456789abcdef"))
+	key := base64.RawStdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	phc := func(version, params, salt, key string) string {
+		return "$argon2id$" + version + "$" + params + "$" + salt + "$" + key
+	}
+
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{"plain password", "correct horse battery staple"},
+		{"empty", ""},
+		{"too few fields", "$argon2id$v=19$m=64,t=1,p=1$" + salt},
+		{"too many fields", phc("v=19", "m=64,t=1,p=1", salt, key) + "$extra"},
+		{"argon2i", "$argon2i$v=19$m=64,t=1,p=1$" + salt + "$" + key},
+		{"old version", phc("v=16", "m=64,t=1,p=1", salt, key)},
+		{"unparseable params", phc("v=19", "m=lots,t=1,p=1", salt, key)},
+		{"zero iterations", phc("v=19", "m=1,t=0,p=1", salt, key)},
+		{"zero parallelism", phc("v=19", "m=64,t=1,p=0", salt, key)},
+		{"parallelism overflow", phc("v=19", "m=64,t=1,p=300", salt, key)},
+		{"memory below 8 KiB per lane", phc("v=19", "m=15,t=1,p=2", salt, key)},
+		{"memory too large", phc("v=19", "m=4294967295,t=1,p=1", salt, key)},
+		{"too many iterations", phc("v=19", "m=64,t=4294967295,p=1", salt, key)},
+		{"salt not base64", phc("v=19", "m=64,t=1,p=1", "!!!", key)},
+		{"salt too short", phc("v=19", "m=64,t=1,p=1", base64.RawStdEncoding.EncodeToString([]byte("abc")), key)},
+		{"empty key", phc("v=19", "m=64,t=1,p=1", salt, "")},
+		{"key too long", phc("v=19", "m=64,t=1,p=1", salt, base64.RawStdEncoding.EncodeToString(make([]byte, 4096)))},
+		{"bcrypt prefix only", "$2a$10$"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := Verify("anything", tt.encoded)
+			if err == nil {
+				t.Errorf("Verify() = %v, nil; want an error", ok)
+			}
+			if ok {
+				t.Error("malformed hash verified")
+			}
+			if !NeedsRehash(tt.encoded) {
+				t.Error("malformed hash does not need a rehash")
+			}
+		})
+	}
+}
+
+func TestInitHasherRejectsUnusableArgon2idParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		config configs.Config
+	}{
+		{"zero iterations", configs.Config{Argon2Memory: 65536, Argon2Iterations: 0, Argon2Parallelism: 2}},
+		{"zero parallelism", configs.Config{Argon2Memory: 65536, Argon2Iterations: 3, Argon2Parallelism: 0}},
+		{"parallelism overflow", configs.Config{Argon2Memory: 65536, Argon2Iterations: 3, Argon2Parallelism: 256}},
+		{"memory too large", configs.Config{Argon2Memory: maxArgon2idMemory + 1, Argon2Iterations: 3, Argon2Parallelism: 2}},
+		{"negative memory", configs.Config{Argon2Memory: -1, Argon2Iterations: 3, Argon2Parallelism: 2}},
+		{"unknown scheme", configs.Config{Argon2Memory: 65536, Argon2Iterations: 3, Argon2Parallelism: 2, PasswordHashScheme: "md5"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.RLock()
+			oldPreferred, oldFallbacks := preferred, fallbacks
+			mu.RUnlock()
+			defer func() {
+				mu.Lock()
+				preferred, fallbacks = oldPreferred, oldFallbacks
+				mu.Unlock()
+			}()
+
+			config := tt.config
+			config.BcryptCost = bcrypt.MinCost
+			if config.PasswordHashScheme == "" {
+				config.PasswordHashScheme = "argon2id"
+			}
+			if err := InitHasher(&config); err == nil {
+				t.Error("InitHasher() accepted unusable settings")
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package password
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/yourusername/ecommerce/configs"
+)
+
+// Violation codes
+const (
+	CodeTooShort     = "too_short"
+	CodeTooLong      = "too_long"
+	CodeTooWeak      = "too_weak"
+	CodeEmailDerived = "email_derived"
+	CodeBreached     = "breached"
+)
+
+// Violation is one reason a password was rejected
+type Violation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Policy is the set of rules a new password has to satisfy
+type Policy struct {
+	MinLength          int
+	MaxLength          int
+	MinEntropyBits     float64
+	RejectEmailDerived bool
+}
+
+// Validator checks new passwords against the policy and the breached password list
+type Validator struct {
+	policy   Policy
+	breaches *BreachList
+}
+
+// NewValidator creates a validator from the PASSWORD_* settings, loading the
+// breached password list if one is configured
+func NewValidator(config *configs.Config) (*Validator, error) {
+	validator := &Validator{
+		policy: Policy{
+			MinLength:          config.PasswordMinLength,
+			MaxLength:          config.PasswordMaxLength,
+			MinEntropyBits:     float64(config.PasswordMinEntropy),
+			RejectEmailDerived: config.PasswordRejectEmailDerived,
+		},
+	}
+
+	if config.BreachedPasswordsFile != "" {
+		breaches, err := LoadBreachList(config.BreachedPasswordsFile)
+		if err != nil {
+			return nil, err
+		}
+		validator.breaches = breaches
+	}
+
+	return validator, nil
+}
+
+// Validate returns every rule the password breaks for an account with the given email
+func (v *Validator) Validate(password, email string) []Violation {
+	var violations []Violation
+
+	length := utf8.RuneCountInString(password)
+	if length < v.policy.MinLength {
+		violations = append(violations, Violation{CodeTooShort, "Password must be at least " + strconv.Itoa(v.policy.MinLength) + " characters"})
+	}
+	if v.policy.MaxLength > 0 && length > v.policy.MaxLength {
+		violations = append(violations, Violation{CodeTooLong, "Password must be at most " + strconv.Itoa(v.policy.MaxLength) + " characters"})
+	}
+	if length >= v.policy.MinLength && EstimateEntropy(password) < v.policy.MinEntropyBits {
+		violations = append(violations, Violation{CodeTooWeak, "Password is too easy to guess; use a longer or more varied password"})
+	}
+	if v.policy.RejectEmailDerived && derivedFromEmail(password, email) {
+		violations = append(violations, Violation{CodeEmailDerived, "Password must not be based on your email address"})
+	}
+	if v.breaches != nil && v.breaches.Contains(password) {
+		violations = append(violations, Violation{CodeBreached, "Password has appeared in a data breach; choose a different one"})
+	}
+
+	return violations
+}
+
+// EstimateEntropy gives a rough strength estimate in bits: the size of the
+// character classes used, per character, with repeated and sequential
+// characters counting for less
+func EstimateEntropy(password string) float64 {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	pool := 0
+	if lower {
+		pool += 26
+	}
+	if upper {
+		pool += 26
+	}
+	if digit {
+		pool += 10
+	}
+	if symbol {
+		pool += 33
+	}
+	if pool == 0 {
+		return 0
+	}
+
+	effective := 0.0
+	var prev rune
+	for i, r := range password {
+		if i > 0 && (r == prev || r == prev+1 || r == prev-1) {
+			effective += 0.25
+		} else {
+			effective++
+		}
+		prev = r
+	}
+
+	return effective * math.Log2(float64(pool))
+}
+
+// derivedFromEmail reports whether the password is built around the email's
+// local part, e.g. jane.doe@example.com and "JaneDoe2024!"
+func derivedFromEmail(password, email string) bool {
+	local, _, _ := strings.Cut(email, "@")
+	local = alphanumeric(local)
+	if len(local) < 4 {
+		return false
+	}
+
+	normalized := alphanumeric(password)
+	if strings.Contains(normalized, local) {
+		return true
+	}
+	// The password is mostly a piece of the email
+	return len(normalized) >= 4 && strings.Contains(local, normalized)
+}
+
+func alphanumeric(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,130 @@
+package password
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/ecommerce/configs"
+)
+
+// sha1Hex returns the upper-case hex SHA-1 of a password, as breach lists store it
+func sha1Hex(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func writeBreachList(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestValidate(t *testing.T) {
+	breaches := writeBreachList(t, sha1Hex("Tr0ub4dor&3xyz")+":1234\n")
+	validator, err := NewValidator(&configs.Config{
+		PasswordMinLength:          10,
+		PasswordMaxLength:          64,
+		PasswordMinEntropy:         40,
+		PasswordRejectEmailDerived: true,
+		BreachedPasswordsFile:      breaches,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		want     []string
+	}{
+		{"strong", "correct-Horse-battery-9", nil},
+		{"too short", "aB3$xY", []string{CodeTooShort}},
+		{"too long", strings.Repeat("aB3$", 17), []string{CodeTooLong}},
+		{"repeated characters", "aaaaaaaaaaaa", []string{CodeTooWeak}},
+		{"sequential characters", "abcdefghijkl", []string{CodeTooWeak}},
+		{"contains email local part", "JaneDoe-2024-xyz!", []string{CodeEmailDerived}},
+		{"breached", "Tr0ub4dor&3xyz", []string{CodeBreached}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, violation := range validator.Validate(tt.password, "jane.doe@example.com") {
+				got = append(got, violation.Code)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Validate(%q) = %v, want %v", tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDerivedFromEmail(t *testing.T) {
+	tests := []struct {
+		password string
+		email    string
+		want     bool
+	}{
+		{"JaneDoe2024!", "jane.doe@example.com", true},
+		{"jane.doe", "jane.doe@example.com", true},
+		{"janedo", "jane.doe@example.com", true},
+		{"correct-horse", "jane.doe@example.com", false},
+		// Local parts too short to be meaningful are ignored
+		{"joe-rocks-123", "joe@example.com", false},
+	}
+
+	for _, tt := range tests {
+		if got := derivedFromEmail(tt.password, tt.email); got != tt.want {
+			t.Errorf("derivedFromEmail(%q, %q) = %v, want %v", tt.password, tt.email, got, tt.want)
+		}
+	}
+}
+
+func TestBreachList(t *testing.T) {
+	path := writeBreachList(t, strings.Join([]string{
+		"# comment",
+		"",
+		sha1Hex("password123"),
+		strings.ToLower(sha1Hex("letmein")) + ":42",
+	}, "\n"))
+
+	list, err := LoadBreachList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for password, want := range map[string]bool{
+		"password123": true,
+		"letmein":     true,
+		"Password123": false,
+		"":            false,
+	} {
+		if got := list.Contains(password); got != want {
+			t.Errorf("Contains(%q) = %v, want %v", password, got, want)
+		}
+	}
+}
+
+func TestLoadBreachListRejectsInvalidLines(t *testing.T) {
+	for name, content := range map[string]string{
+		"short hash": "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD\n",
+		"not hex":    strings.Repeat("Z", 40) + "\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := LoadBreachList(writeBreachList(t, content)); err == nil {
+				t.Error("LoadBreachList() accepted an invalid line")
+			}
+		})
+	}
+
+	if _, err := LoadBreachList(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("LoadBreachList() accepted a missing file")
+	}
+}
@@ -24,7 +24,9 @@ func (s *StripeService) CreatePaymentIntent(amount int64, currency string, metad
 	params := &stripe.PaymentIntentParams{
 		Amount:   stripe.Int64(amount),
 		Currency: stripe.String(currency),
-		Metadata: metadata,
+	}
+	for key, value := range metadata {
+		params.AddMetadata(key, value)
 	}
 
 	return paymentintent.New(params)
@@ -0,0 +1,143 @@
+package rbac
+
+import (
+	"github.com/yourusername/ecommerce/internal/database"
+	"github.com/yourusername/ecommerce/internal/models"
+	"gorm.io/gorm"
+)
+
+// Permissions
+const (
+	CatalogWrite     = "catalog:write"
+	OrdersRead       = "orders:read"
+	OrdersRefund     = "orders:refund"
+	UsersRead        = "users:read"
+	UsersManage      = "users:manage"
+	UsersImpersonate = "users:impersonate"
+	RolesManage      = "roles:manage"
+	APIKeysManage    = "api_keys:manage"
+	AuditRead        = "audit:read"
+)
+
+// Built-in roles
+const (
+	RoleAdmin   = "admin"
+	RoleUser    = "user"
+	RoleSupport = "support"
+)
+
+// permissionDescriptions lists every permission the application checks
+var permissionDescriptions = map[string]string{
+	CatalogWrite:     "Create, update and delete products",
+	OrdersRead:       "View any customer's orders",
+	OrdersRefund:     "Refund orders",
+	UsersRead:        "View user accounts",
+	UsersManage:      "Manage user accounts",
+	UsersImpersonate: "Sign in as a customer to see what they see",
+	RolesManage:      "Manage roles and their permissions",
+	APIKeysManage:    "Create and revoke API keys",
+	AuditRead:        "View the security audit log",
+}
+
+// defaultRoles are created on startup if missing. The admin role always gets
+// every permission.
+var defaultRoles = []struct {
+	name        string
+	description string
+	permissions []string
+}{
+	{RoleAdmin, "Full access", nil},
+	{RoleUser, "Customer", []string{}},
+	{RoleSupport, "Customer support", []string{OrdersRead, UsersRead, UsersImpersonate}},
+}
+
+// Seed creates the known permissions and the default roles
+func Seed(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for name, description := range permissionDescriptions {
+			permission := models.Permission{Name: name}
+			if err := tx.Where(models.Permission{Name: name}).
+				Assign(models.Permission{Description: description}).
+				FirstOrCreate(&permission).Error; err != nil {
+				return err
+			}
+		}
+
+		var allPermissions []models.Permission
+		if err := tx.Find(&allPermissions).Error; err != nil {
+			return err
+		}
+
+		for _, defaultRole := range defaultRoles {
+			var role models.Role
+			result := tx.Where("name = ?", defaultRole.name).Limit(1).Find(&role)
+			if result.Error != nil {
+				return result.Error
+			}
+
+			if defaultRole.name == RoleAdmin {
+				if result.RowsAffected == 0 {
+					role = models.Role{Name: defaultRole.name, Description: defaultRole.description}
+					if err := tx.Create(&role).Error; err != nil {
+						return err
+					}
+				}
+				if err := tx.Model(&role).Association("Permissions").Replace(allPermissions); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if result.RowsAffected > 0 {
+				continue
+			}
+
+			var permissions []models.Permission
+			if len(defaultRole.permissions) > 0 {
+				if err := tx.Where("name IN ?", defaultRole.permissions).Find(&permissions).Error; err != nil {
+					return err
+				}
+			}
+			role = models.Role{Name: defaultRole.name, Description: defaultRole.description, Permissions: permissions}
+			if err := tx.Create(&role).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// IsKnownPermission reports whether a permission name is one the application checks
+func IsKnownPermission(name string) bool {
+	_, ok := permissionDescriptions[name]
+	return ok
+}
+
+// PermissionsForRole returns the names of the permissions granted to a role
+func PermissionsForRole(roleName string) ([]string, error) {
+	var names []string
+	err := database.GetDB().Model(&models.Permission{}).
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Joins("JOIN roles ON roles.id = role_permissions.role_id").
+		Where("roles.name = ?", roleName).
+		Order("permissions.name").
+		Pluck("permissions.name", &names).Error
+	return names, err
+}
+
+// RoleExists reports whether a role with the given name exists
+func RoleExists(roleName string) (bool, error) {
+	var count int64
+	err := database.GetDB().Model(&models.Role{}).Where("name = ?", roleName).Count(&count).Error
+	return count > 0, err
+}
+
+// PromoteAdmin gives the account with the given email the admin role. It is
+// how the first admin is created; a missing account is not an error.
+func PromoteAdmin(db *gorm.DB, email string) (bool, error) {
+	result := db.Model(&models.User{}).
+		Where("email = ? AND role <> ?", email, RoleAdmin).
+		Update("role", RoleAdmin)
+	return result.RowsAffected > 0, result.Error
+}
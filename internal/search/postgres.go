@@ -0,0 +1,195 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// languagePattern limits the text search configuration name, which is
+// written into the trigger function
+var languagePattern = regexp.MustCompile(`^[a-z_]+$`)
+
+// Markers ts_headline puts around matched words. They are control characters
+// stripped from the product text first, so any in a headline came from
+// ts_headline and can be turned into <mark> tags once the text is escaped.
+const (
+	matchStart = "\x02"
+	matchStop  = "\x03"
+)
+
+// ts_headline options: the whole name is kept, descriptions are cut down to
+// the fragments around the matches
+const (
+	nameHeadlineOptions    = "StartSel=" + matchStart + ", StopSel=" + matchStop + ", HighlightAll=true"
+	snippetHeadlineOptions = "StartSel=" + matchStart + ", StopSel=" + matchStop + ", MaxWords=30, MinWords=10, MaxFragments=2"
+)
+
+// highlightReplacer escapes text for HTML and turns the match markers into <mark> tags
+var highlightReplacer = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&#34;",
+	"'", "&#39;",
+	matchStart, "<mark>",
+	matchStop, "</mark>",
+)
+
+// PostgresEngine searches products with a weighted tsvector kept up to date
+// by triggers, falling back to trigram similarity on the name for typos
+type PostgresEngine struct {
+	db       *gorm.DB
+	language string
+}
+
+// NewPostgresEngine creates a search engine using the given text search
+// configuration, such as english or german, for stemming
+func NewPostgresEngine(db *gorm.DB, language string) *PostgresEngine {
+	return &PostgresEngine{db: db, language: language}
+}
+
+// Migrate adds the products.search_vector column, the triggers that maintain
+// it and the indexes searches use. The vector weights the product name
+// highest, then the description, then the category name. Changing the
+// language rebuilds every product's vector.
+func Migrate(db *gorm.DB, language string) error {
+	if !languagePattern.MatchString(language) {
+		return fmt.Errorf("invalid search language %q", language)
+	}
+	var valid bool
+	if err := db.Raw("SELECT EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = ?)", language).Scan(&valid).Error; err != nil {
+		return err
+	}
+	if !valid {
+		return fmt.Errorf("unknown text search configuration %q", language)
+	}
+
+	// The language the vectors were last built with
+	var previous *string
+	if err := db.Raw("SELECT obj_description(to_regproc('products_search_vector_update'), 'pg_proc')").Scan(&previous).Error; err != nil {
+		return err
+	}
+
+	err := db.Exec(fmt.Sprintf(`
+		CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+		ALTER TABLE products ADD COLUMN IF NOT EXISTS search_vector tsvector;
+
+		CREATE OR REPLACE FUNCTION products_search_vector_update() RETURNS trigger AS $$
+		BEGIN
+			NEW.search_vector :=
+				setweight(to_tsvector('%[1]s', coalesce(NEW.name, '')), 'A') ||
+				setweight(to_tsvector('%[1]s', coalesce(NEW.description, '')), 'B') ||
+				setweight(to_tsvector('%[1]s', coalesce((SELECT name FROM categories WHERE id = NEW.category_id), '')), 'C');
+			RETURN NEW;
+		END;
+		$$ LANGUAGE plpgsql;
+		COMMENT ON FUNCTION products_search_vector_update() IS '%[1]s';
+
+		DROP TRIGGER IF EXISTS products_search_vector_update ON products;
+		CREATE TRIGGER products_search_vector_update BEFORE INSERT OR UPDATE OF name, description, category_id ON products
+			FOR EACH ROW EXECUTE FUNCTION products_search_vector_update();
+
+		CREATE OR REPLACE FUNCTION categories_search_vector_update() RETURNS trigger AS $$
+		BEGIN
+			UPDATE products SET category_id = category_id WHERE category_id = NEW.id;
+			RETURN NULL;
+		END;
+		$$ LANGUAGE plpgsql;
+
+		DROP TRIGGER IF EXISTS categories_search_vector_update ON categories;
+		CREATE TRIGGER categories_search_vector_update AFTER UPDATE OF name ON categories
+			FOR EACH ROW WHEN (OLD.name IS DISTINCT FROM NEW.name)
+			EXECUTE FUNCTION categories_search_vector_update();
+
+		CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING GIN (search_vector);
+		CREATE INDEX IF NOT EXISTS idx_products_name_trgm ON products USING GIN (name gin_trgm_ops);
+	`, language)).Error
+	if err != nil {
+		return err
+	}
+
+	// Fill in products created before search existed, or all of them if the language changed
+	if previous == nil || *previous != language {
+		return db.Exec("UPDATE products SET category_id = category_id").Error
+	}
+	return db.Exec("UPDATE products SET category_id = category_id WHERE search_vector IS NULL").Error
+}
+
+// prefixQuery turns search text into a tsquery matching every word, with
+// the last one matched as a prefix so results appear while typing
+func prefixQuery(text string) string {
+	terms := Terms(text)
+	if len(terms) == 0 {
+		return ""
+	}
+	terms[len(terms)-1] += ":*"
+	return strings.Join(terms, " & ")
+}
+
+// Search ranks products whose vector matches every word of the query. If
+// nothing matches, products with a similar name are returned instead.
+func (e *PostgresEngine) Search(ctx context.Context, query Query) (*Results, error) {
+	results := &Results{Hits: []Hit{}}
+	tsquery := prefixQuery(query.Text)
+	if tsquery == "" {
+		return results, nil
+	}
+
+	db := e.db.WithContext(ctx)
+	match := func() *gorm.DB {
+		return db.Table("products").Where("search_vector @@ to_tsquery(?::regconfig, ?)", e.language, tsquery)
+	}
+
+	if err := match().Count(&results.Total).Error; err != nil {
+		return nil, err
+	}
+	if results.Total > 0 {
+		err := match().
+			Select(`id AS product_id,
+				ts_rank_cd(search_vector, to_tsquery(?::regconfig, ?)) AS rank,
+				ts_headline(?::regconfig, translate(name, ?, ''), to_tsquery(?::regconfig, ?), ?) AS name_highlight,
+				ts_headline(?::regconfig, translate(coalesce(description, ''), ?, ''), to_tsquery(?::regconfig, ?), ?) AS snippet`,
+				e.language, tsquery,
+				e.language, matchStart+matchStop, e.language, tsquery, nameHeadlineOptions,
+				e.language, matchStart+matchStop, e.language, tsquery, snippetHeadlineOptions).
+			Order("rank DESC, id").
+			Offset(query.Offset).Limit(query.Limit).
+			Scan(&results.Hits).Error
+		escapeHighlights(results.Hits)
+		return results, err
+	}
+
+	// Nothing matched; try names that are spelled similarly
+	text := strings.Join(Terms(query.Text), " ")
+	fuzzy := func() *gorm.DB {
+		return db.Table("products").Where("name % ?", text)
+	}
+	if err := fuzzy().Count(&results.Total).Error; err != nil {
+		return nil, err
+	}
+	results.Fuzzy = true
+	err := fuzzy().
+		Select(`id AS product_id, similarity(name, ?) AS rank,
+			translate(name, ?, '') AS name_highlight,
+			translate(left(coalesce(description, ''), 160), ?, '') AS snippet`,
+			text, matchStart+matchStop, matchStart+matchStop).
+		Order("rank DESC, id").
+		Offset(query.Offset).Limit(query.Limit).
+		Scan(&results.Hits).Error
+	escapeHighlights(results.Hits)
+	return results, err
+}
+
+// escapeHighlights makes the hits' name and snippet safe to insert into a
+// page: product text is HTML-escaped and only the match markers become markup
+func escapeHighlights(hits []Hit) {
+	for i := range hits {
+		hits[i].NameHighlight = highlightReplacer.Replace(hits[i].NameHighlight)
+		hits[i].Snippet = highlightReplacer.Replace(hits[i].Snippet)
+	}
+}
@@ -0,0 +1,140 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/logger"
+)
+
+func TestEscapeHighlights(t *testing.T) {
+	tests := []struct {
+		name        string
+		headline    string
+		snippet     string
+		wantName    string
+		wantSnippet string
+	}{
+		{
+			"plain text",
+			"Red \x02Shirt\x03",
+			"A soft cotton \x02shirt\x03",
+			"Red <mark>Shirt</mark>",
+			"A soft cotton <mark>shirt</mark>",
+		},
+		{
+			"markup in the name",
+			"<img src=x onerror=\"alert(1)\"> \x02Shirt\x03",
+			"",
+			"&lt;img src=x onerror=&#34;alert(1)&#34;&gt; <mark>Shirt</mark>",
+			"",
+		},
+		{
+			"markup around a match in the description",
+			"Mug",
+			"<script>\x02mug\x03</script> & <b>'saucer'</b>",
+			"Mug",
+			"&lt;script&gt;<mark>mug</mark>&lt;/script&gt; &amp; &lt;b&gt;&#39;saucer&#39;&lt;/b&gt;",
+		},
+		{
+			"product text that looks like a highlight",
+			"<mark>Sale</mark>",
+			"&lt;b&gt;",
+			"&lt;mark&gt;Sale&lt;/mark&gt;",
+			"&amp;lt;b&amp;gt;",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits := []Hit{{NameHighlight: tt.headline, Snippet: tt.snippet}}
+			escapeHighlights(hits)
+			if hits[0].NameHighlight != tt.wantName {
+				t.Errorf("NameHighlight = %q, want %q", hits[0].NameHighlight, tt.wantName)
+			}
+			if hits[0].Snippet != tt.wantSnippet {
+				t.Errorf("Snippet = %q, want %q", hits[0].Snippet, tt.wantSnippet)
+			}
+		})
+	}
+}
+
+// recordingDB returns a database that runs no SQL, records the queries built
+// for it and reports the given number of matches for every count
+func recordingDB(t *testing.T, count int64, statements *[]string) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=invalid"}), &gorm.Config{
+		DryRun:                 true,
+		SkipDefaultTransaction: true,
+		DisableAutomaticPing:   true,
+		Logger:                 logger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record := func(tx *gorm.DB) {
+		*statements = append(*statements, tx.Dialector.Explain(tx.Statement.SQL.String(), tx.Statement.Vars...))
+	}
+	err = db.Callback().Query().Replace("gorm:query", func(tx *gorm.DB) {
+		callbacks.BuildQuerySQL(tx)
+		record(tx)
+		if total, ok := tx.Statement.Dest.(*int64); ok {
+			// Count reads the result from RowsAffected when it isn't one row
+			tx.RowsAffected = 1
+			*total = count
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Scan goes through the row callbacks, which build the query but can't dry run it
+	if err := db.Callback().Row().After("gorm:row").Register("test:record", record); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestSearchStripsMarkersFromProductText(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		count int64
+	}{
+		{"full-text matches", 1},
+		{"fuzzy fallback", 0},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var statements []string
+			engine := NewPostgresEngine(recordingDB(t, tt.count, &statements), "english")
+			_, err := engine.Search(context.Background(), Query{Text: "shirt", Limit: 10})
+			if err != nil && !errors.Is(err, gorm.ErrDryRunModeUnsupported) {
+				t.Fatal(err)
+			}
+
+			var hits string
+			for _, statement := range statements {
+				if strings.Contains(statement, "name_highlight") {
+					hits = statement
+				}
+			}
+			if hits == "" {
+				t.Fatalf("no hits query among %q", statements)
+			}
+			if fullText := strings.Contains(hits, "ts_headline("); fullText != (tt.count > 0) {
+				t.Errorf("hits query uses ts_headline: %v, want %v", fullText, tt.count > 0)
+			}
+			// Text containing the markers could otherwise open its own <mark> tags
+			if got := strings.Count(hits, "translate("); got != 2 {
+				t.Errorf("hits query strips markers from %d columns, want name and description: %s", got, hits)
+			}
+			if strings.Contains(hits, "<mark>") {
+				t.Errorf("hits query asks the database for markup: %s", hits)
+			}
+		})
+	}
+}
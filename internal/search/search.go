@@ -0,0 +1,44 @@
+package search
+
+import (
+	"context"
+	"strings"
+	"unicode"
+)
+
+// Engine finds products matching a text query. The Postgres engine is built
+// in; an external search service can be used by implementing this interface.
+type Engine interface {
+	Search(ctx context.Context, query Query) (*Results, error)
+}
+
+// Query is a product search request
+type Query struct {
+	Text   string
+	Limit  int
+	Offset int
+}
+
+// Hit is one matching product. Highlights are HTML: the product text is
+// escaped and matched words are wrapped in <mark> tags.
+type Hit struct {
+	ProductID     uint    `json:"product_id"`
+	Rank          float64 `json:"rank"`
+	NameHighlight string  `json:"name_highlight"`
+	Snippet       string  `json:"snippet"`
+}
+
+// Results holds a page of hits, best first. Fuzzy is set when nothing
+// matched exactly and the hits come from typo-tolerant matching instead.
+type Results struct {
+	Hits  []Hit `json:"hits"`
+	Total int64 `json:"total"`
+	Fuzzy bool  `json:"fuzzy"`
+}
+
+// Terms splits search text into words, dropping punctuation and operators
+func Terms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
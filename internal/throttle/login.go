@@ -0,0 +1,121 @@
+package throttle
+
+import (
+	"strings"
+	"time"
+
+	"github.com/yourusername/ecommerce/configs"
+	"github.com/yourusername/ecommerce/internal/database"
+)
+
+// LoginGuard throttles login attempts per account and per client IP
+type LoginGuard struct {
+	store     Store
+	window    time.Duration
+	account   *Throttler
+	ip        *Throttler
+	magicLink *Throttler
+}
+
+// NewLoginGuard creates a login guard backed by the store selected by LOGIN_THROTTLE_STORE
+func NewLoginGuard(config *configs.Config) *LoginGuard {
+	var store Store
+	switch config.LoginThrottleStore {
+	case "postgres":
+		store = NewPostgresStore(database.GetDB())
+	default:
+		store = NewMemoryStore()
+	}
+
+	return &LoginGuard{
+		store:  store,
+		window: config.LoginFailureWindow,
+		account: NewThrottler(store, Policy{
+			FreeAttempts:     config.AccountFreeAttempts,
+			BaseDelay:        config.LoginBackoffBase,
+			MaxDelay:         config.LoginBackoffMax,
+			LockoutThreshold: config.AccountLockoutThreshold,
+			LockoutDuration:  config.AccountLockoutDuration,
+			Window:           config.LoginFailureWindow,
+		}),
+		ip: NewThrottler(store, Policy{
+			FreeAttempts:     config.IPFreeAttempts,
+			BaseDelay:        config.LoginBackoffBase,
+			MaxDelay:         config.LoginBackoffMax,
+			LockoutThreshold: config.IPLockoutThreshold,
+			LockoutDuration:  config.IPLockoutDuration,
+			Window:           config.LoginFailureWindow,
+		}),
+		// Every link sent counts; the one that reaches the limit blocks the
+		// email for the rest of the window
+		magicLink: NewThrottler(store, Policy{
+			FreeAttempts: config.MagicLinkMaxEmails - 1,
+			BaseDelay:    config.MagicLinkWindow,
+			MaxDelay:     config.MagicLinkWindow,
+			Window:       config.MagicLinkWindow,
+		}),
+	}
+}
+
+// Wait returns how long a login for the email from the IP must wait
+func (g *LoginGuard) Wait(email, ip string) (time.Duration, error) {
+	accountWait, err := g.account.Wait(accountKey(email))
+	if err != nil {
+		return 0, err
+	}
+	ipWait, err := g.ip.Wait(ipKey(ip))
+	if err != nil {
+		return 0, err
+	}
+
+	if ipWait > accountWait {
+		return ipWait, nil
+	}
+	return accountWait, nil
+}
+
+// Fail records a failed login for both the email and the IP
+func (g *LoginGuard) Fail(email, ip string) error {
+	if err := g.account.Fail(accountKey(email)); err != nil {
+		return err
+	}
+	return g.ip.Fail(ipKey(ip))
+}
+
+// Succeed clears the account's failures. The IP's failures are kept so a
+// successful login to one account doesn't reset guessing against others.
+func (g *LoginGuard) Succeed(email string) error {
+	return g.account.Reset(accountKey(email))
+}
+
+// Unlock lifts an account lockout
+func (g *LoginGuard) Unlock(email string) error {
+	return g.account.Reset(accountKey(email))
+}
+
+// WaitMagicLink returns how long until another login link may be sent to the email
+func (g *LoginGuard) WaitMagicLink(email string) (time.Duration, error) {
+	return g.magicLink.Wait(magicLinkKey(email))
+}
+
+// SentMagicLink counts a login link sent to the email
+func (g *LoginGuard) SentMagicLink(email string) error {
+	return g.magicLink.Fail(magicLinkKey(email))
+}
+
+// Purge drops counters that have aged out of the failure window
+func (g *LoginGuard) Purge() error {
+	return g.store.Purge(time.Now().Add(-g.window))
+}
+
+func accountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+func magicLinkKey(email string) string {
+	return "magic:" + strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,74 @@
+package throttle
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps failure counters in process memory. Counters are lost on
+// restart and not shared between instances.
+type MemoryStore struct {
+	mu       sync.Mutex
+	attempts map[string]*Attempt
+}
+
+// NewMemoryStore creates a new in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		attempts: make(map[string]*Attempt),
+	}
+}
+
+// Get returns the state of a key
+func (s *MemoryStore) Get(key string) (Attempt, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if !ok {
+		return Attempt{}, false, nil
+	}
+	return *attempt, true, nil
+}
+
+// RecordFailure counts a failure for a key
+func (s *MemoryStore) RecordFailure(key string, policy Policy) (Attempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	attempt, ok := s.attempts[key]
+	if !ok || now.Sub(attempt.LastFailureAt) > policy.Window {
+		attempt = &Attempt{Key: key}
+		s.attempts[key] = attempt
+	}
+
+	attempt.Failures++
+	attempt.LastFailureAt = now
+	attempt.LockedUntil = now.Add(policy.Delay(attempt.Failures))
+
+	return *attempt, nil
+}
+
+// Reset forgets a key
+func (s *MemoryStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, key)
+	return nil
+}
+
+// Purge removes stale keys
+func (s *MemoryStore) Purge(before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, attempt := range s.attempts {
+		if attempt.LastFailureAt.Before(before) && attempt.LockedUntil.Before(now) {
+			delete(s.attempts, key)
+		}
+	}
+	return nil
+}
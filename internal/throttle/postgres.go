@@ -0,0 +1,85 @@
+package throttle
+
+import (
+	"time"
+
+	"github.com/yourusername/ecommerce/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PostgresStore keeps failure counters in the login_attempts table so they
+// are shared by every instance and survive restarts
+type PostgresStore struct {
+	db *gorm.DB
+}
+
+// NewPostgresStore creates a new Postgres backed store
+func NewPostgresStore(db *gorm.DB) *PostgresStore {
+	return &PostgresStore{
+		db: db,
+	}
+}
+
+// Get returns the state of a key
+func (s *PostgresStore) Get(key string) (Attempt, bool, error) {
+	var record models.LoginAttempt
+	result := s.db.Where("key = ?", key).Limit(1).Find(&record)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return Attempt{}, false, result.Error
+	}
+	return toAttempt(record), true, nil
+}
+
+// RecordFailure counts a failure for a key. The row is locked for the
+// read-modify-write so concurrent failures are not lost.
+func (s *PostgresStore) RecordFailure(key string, policy Policy) (Attempt, error) {
+	var record models.LoginAttempt
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.LoginAttempt{Key: key, LastFailureAt: now}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("key = ?", key).First(&record).Error; err != nil {
+			return err
+		}
+
+		if now.Sub(record.LastFailureAt) > policy.Window {
+			record.Failures = 0
+		}
+		record.Failures++
+		record.LastFailureAt = now
+		record.LockedUntil = now.Add(policy.Delay(record.Failures))
+
+		return tx.Save(&record).Error
+	})
+	if err != nil {
+		return Attempt{}, err
+	}
+
+	return toAttempt(record), nil
+}
+
+// Reset forgets a key
+func (s *PostgresStore) Reset(key string) error {
+	return s.db.Where("key = ?", key).Delete(&models.LoginAttempt{}).Error
+}
+
+// Purge removes stale keys
+func (s *PostgresStore) Purge(before time.Time) error {
+	return s.db.Where("last_failure_at < ? AND locked_until < ?", before, time.Now()).
+		Delete(&models.LoginAttempt{}).Error
+}
+
+func toAttempt(record models.LoginAttempt) Attempt {
+	return Attempt{
+		Key:           record.Key,
+		Failures:      record.Failures,
+		LastFailureAt: record.LastFailureAt,
+		LockedUntil:   record.LockedUntil,
+	}
+}
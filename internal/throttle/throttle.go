@@ -0,0 +1,96 @@
+package throttle
+
+import (
+	"time"
+)
+
+// Attempt is the failure state of one throttling key
+type Attempt struct {
+	Key           string
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   time.Time
+}
+
+// Store persists failure counters. Implementations must make RecordFailure
+// atomic so concurrent failures are all counted.
+type Store interface {
+	// Get returns the state of a key; ok is false if it has no recorded failures
+	Get(key string) (attempt Attempt, ok bool, err error)
+	// RecordFailure counts a failure and sets how long the key is blocked for
+	RecordFailure(key string, policy Policy) (Attempt, error)
+	// Reset forgets a key's failures and lifts any lockout
+	Reset(key string) error
+	// Purge removes keys whose last failure is before the cutoff and that are no longer locked
+	Purge(before time.Time) error
+}
+
+// Policy decides how long a key is blocked after a number of failures. The
+// first FreeAttempts failures cost nothing, after that the delay doubles from
+// BaseDelay up to MaxDelay, and at LockoutThreshold the key is locked out.
+type Policy struct {
+	FreeAttempts     int
+	BaseDelay        time.Duration
+	MaxDelay         time.Duration
+	LockoutThreshold int
+	LockoutDuration  time.Duration
+	Window           time.Duration // failures older than this are forgotten
+}
+
+// Delay returns how long a key is blocked after the given number of failures
+func (p Policy) Delay(failures int) time.Duration {
+	if p.LockoutThreshold > 0 && failures >= p.LockoutThreshold {
+		return p.LockoutDuration
+	}
+	if failures <= p.FreeAttempts {
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for i := p.FreeAttempts + 1; i < failures; i++ {
+		delay *= 2
+		if delay >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	return delay
+}
+
+// Throttler applies a policy to keys kept in a store
+type Throttler struct {
+	store  Store
+	policy Policy
+}
+
+// NewThrottler creates a new throttler
+func NewThrottler(store Store, policy Policy) *Throttler {
+	return &Throttler{
+		store:  store,
+		policy: policy,
+	}
+}
+
+// Wait returns how long the key must wait before it may try again
+func (t *Throttler) Wait(key string) (time.Duration, error) {
+	attempt, ok, err := t.store.Get(key)
+	if err != nil || !ok {
+		return 0, err
+	}
+
+	wait := time.Until(attempt.LockedUntil)
+	if wait < 0 {
+		return 0, nil
+	}
+	return wait, nil
+}
+
+// Fail records a failed attempt for the key
+func (t *Throttler) Fail(key string) error {
+	_, err := t.store.RecordFailure(key, t.policy)
+	return err
+}
+
+// Reset clears the key's failures
+func (t *Throttler) Reset(key string) error {
+	return t.store.Reset(key)
+}
@@ -0,0 +1,123 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the RFC 6238 SHA-1 test key "12345678901234567890" in base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestValidateRFCVectors(t *testing.T) {
+	// The last six digits of the RFC 6238 appendix B SHA-1 values
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		at := time.Unix(tt.unix, 0)
+		step, ok := Validate(rfcSecret, tt.code, at, 0)
+		if !ok {
+			t.Errorf("Validate(%s at %d) rejected a valid code", tt.code, tt.unix)
+			continue
+		}
+		if step != Step(at) {
+			t.Errorf("Validate(%s at %d) step = %d, want %d", tt.code, tt.unix, step, Step(at))
+		}
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	key, _ := encoding.DecodeString(rfcSecret)
+	now := time.Unix(1700000000, 0)
+	current := Step(now)
+
+	for offset := int64(-3); offset <= 3; offset++ {
+		code := generate(key, current+offset)
+		step, ok := Validate(rfcSecret, code, now, 0)
+
+		wantOK := offset >= -Skew && offset <= Skew
+		if ok != wantOK {
+			t.Errorf("offset %d: ok = %v, want %v", offset, ok, wantOK)
+		}
+		if ok && step != current+offset {
+			t.Errorf("offset %d: step = %d, want %d", offset, step, current+offset)
+		}
+	}
+}
+
+func TestValidateRejectsReplay(t *testing.T) {
+	key, _ := encoding.DecodeString(rfcSecret)
+	now := time.Unix(1700000000, 0)
+	code := generate(key, Step(now))
+
+	step, ok := Validate(rfcSecret, code, now, 0)
+	if !ok {
+		t.Fatal("first use rejected")
+	}
+	if _, ok := Validate(rfcSecret, code, now, step); ok {
+		t.Error("replayed code accepted")
+	}
+
+	// A code from an earlier step inside the window is also too old once a later one was used
+	earlier := generate(key, Step(now)-1)
+	if _, ok := Validate(rfcSecret, earlier, now, step); ok {
+		t.Error("code older than the last used step accepted")
+	}
+	if _, ok := Validate(rfcSecret, code, now, step-1); !ok {
+		t.Error("code newer than the last used step rejected")
+	}
+}
+
+func TestValidateInput(t *testing.T) {
+	at := time.Unix(59, 0)
+
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+		want   bool
+	}{
+		{"lower-case secret", strings.ToLower(rfcSecret), "287082", true},
+		{"secret with surrounding space", "  " + rfcSecret + "\n", "287082", true},
+		{"code with spaces", rfcSecret, " 287 082 ", true},
+		{"invalid base32", "NOT-BASE32!", "287082", false},
+		{"empty secret", "", "287082", false},
+		{"short code", rfcSecret, "28708", false},
+		{"long code", rfcSecret, "2870820", false},
+		{"wrong code", rfcSecret, "287083", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := Validate(tt.secret, tt.code, at, 0); ok != tt.want {
+				t.Errorf("Validate() ok = %v, want %v", ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(secret, "=") {
+		t.Errorf("secret %q is padded", secret)
+	}
+	key, err := encoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("secret %q is not base32: %v", secret, err)
+	}
+	if len(key) != 20 {
+		t.Errorf("secret has %d bytes, want 20", len(key))
+	}
+}